@@ -0,0 +1,28 @@
+package reason
+
+// Actionable implementers advertise the actions currently available on a
+// resource, such as "cancel" for an order that is still pending, so clients
+// can follow the state of a resource without duplicating its rules. The
+// actions are added to the JSON representation as "_actions"; a nil result
+// leaves the representation unchanged.
+type Actionable interface {
+	AvailableActions(resource interface{}) []string
+}
+
+// withActions adds the available actions of a resource to its JSON
+// representation.
+func withActions(res, rep interface{}, actionable Actionable) (interface{}, error) {
+	actions := actionable.AvailableActions(res)
+	if actions == nil {
+		return rep, nil
+	}
+
+	obj, err := newJSONObject(rep)
+	if err != nil {
+		return nil, err
+	}
+	if err := obj.Set("_actions", actions); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
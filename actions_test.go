@@ -0,0 +1,68 @@
+package reason
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type ActionHandler struct {
+	TestResourceHandler
+}
+
+func (ah ActionHandler) Path() string {
+	return "actions"
+}
+
+func (ah ActionHandler) AvailableActions(resource interface{}) []string {
+	switch resource.(TestResource).ID {
+	case 1:
+		return []string{"cancel", "ship"}
+	}
+	return nil
+}
+
+func (ah ActionHandler) ComputedFields(resource interface{}) map[string]interface{} {
+	return map[string]interface{}{"size": len(resource.(TestResource).Name)}
+}
+
+func TestActionable(t *testing.T) {
+	var requests = []struct {
+		Path       string
+		StatusCode int
+		Body       string
+	}{
+		{"/actions/1", 200, `{"id":1,"name":"The Test","size":8,"_actions":["cancel","ship"]}`},
+		{"/actions/2", 200, `{"id":2,"name":"The Other","size":9}`},
+		{"/actions", 200, `[{"id":1,"name":"The Test","size":8,"_actions":["cancel","ship"]},{"id":2,"name":"The Other","size":9}]`},
+		{"/test/1", 200, `{"id":1,"name":"The Test"}`},
+	}
+
+	s := New()
+	s.Add(TestResource{}, ActionHandler{})
+	s.Add(TestResource{}, TestResourceHandler{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		res, err := http.Get(ts.URL + request.Path)
+		if err != nil {
+			t.Fatalf("%s: expected no error from request, got %s", request.Path, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Path, request.StatusCode, res.StatusCode)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Path, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Path, request.Body, body)
+		}
+	}
+}
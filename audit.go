@@ -0,0 +1,100 @@
+package reason
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+)
+
+// auditKey is the key of the audit object in the JSON representation of
+// audited resources.
+const auditKey = "_audit"
+
+// WithAudit audits the resource: string fields tagged `reason:"createdBy"`
+// and `reason:"updatedBy"` are set to the "sub" claim of the request, see
+// ClaimsFromContext, on create and on create and update respectively, and the
+// actor and timestamp fields, see SetTimestamps, are written in an _audit
+// object instead of at the top level of the resource:
+//
+//	{"id":1,"name":"Jo","_audit":{"created_at":"2024-05-01T10:00:00Z","created_by":"alice"}}
+//
+// Fields tagged createdBy are then immutable.
+func WithAudit() ResourceOption {
+	return func(reg *registration) {
+		reg.audit = true
+	}
+}
+
+// auditNames are the names of the audit fields in the _audit object.
+var auditNames = []struct {
+	name  string
+	field func(formField) bool
+}{
+	{"created_at", func(f formField) bool { return f.createdAt }},
+	{"created_by", func(f formField) bool { return f.createdBy }},
+	{"updated_at", func(f formField) bool { return f.updatedAt }},
+	{"updated_by", func(f formField) bool { return f.updatedBy }},
+}
+
+// stampActors returns a copy of data, a parsed resource of the schema of reg,
+// with its actor fields set for op to the subject of the request's claims.
+func (s *Server) stampActors(r *http.Request, reg *registration, data interface{}, op Operation) (interface{}, error) {
+	t := reflect.TypeOf(reg.schema)
+	if !reg.audit || t == nil || t.Kind() != reflect.Struct || reflect.TypeOf(data) != t {
+		return data, nil
+	}
+	actor, _ := ClaimsFromContext(r.Context())["sub"].(string)
+
+	fields, err := s.getSchemaFields(schemaKey{typ: t, tag: s.tagName})
+	if err != nil {
+		return nil, err
+	}
+
+	val := reflect.New(t).Elem()
+	val.Set(reflect.ValueOf(data))
+	for _, field := range fields {
+		if field.updatedBy || (field.createdBy && op == OperationCreate) {
+			settableField(val, field.index).SetString(actor)
+		}
+	}
+	return val.Interface(), nil
+}
+
+// auditFields moves the audit fields of the struct resource res in rep, its
+// representation, into an _audit object.
+func (s *Server) auditFields(res interface{}, rep interface{}) (interface{}, error) {
+	if _, ok := res.(json.Marshaler); ok {
+		return rep, nil
+	}
+	val := reflect.Indirect(reflect.ValueOf(res))
+	if val.Kind() != reflect.Struct {
+		return rep, nil
+	}
+
+	fields, err := s.getSchemaFields(schemaKey{typ: val.Type()})
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := newJSONObject(rep)
+	if err != nil {
+		return nil, err
+	}
+	audit := &jsonObject{values: make(map[string]json.RawMessage)}
+	for _, name := range auditNames {
+		for _, field := range fields {
+			if !name.field(field) {
+				continue
+			}
+			if value, ok := obj.values[field.name]; ok {
+				audit.setRaw(name.name, value)
+				obj.Delete(field.name)
+			}
+			break
+		}
+	}
+	if err := obj.Set(auditKey, audit); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
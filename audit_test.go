@@ -0,0 +1,53 @@
+package reason
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type ActorResource struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	CreatedBy string `json:"created_by" reason:"createdBy"`
+	UpdatedBy string `json:"updated_by" reason:"updatedBy"`
+}
+
+func TestWithAudit(t *testing.T) {
+	var requests = []struct {
+		Method string
+		Path   string
+		Actor  string
+		Data   url.Values
+		Body   string
+	}{
+		{"POST", "/audited", "alice", url.Values{"name": {"Doc"}, "created_by": {"mallory"}}, `{"id":1,"name":"Doc","_audit":{"created_by":"alice","updated_by":"alice"}}`},
+		{"POST", "/audited/1", "bob", url.Values{"name": {"Edited"}, "created_by": {"bob"}}, `{"id":1,"name":"Edited","_audit":{"created_by":"alice","updated_by":"bob"}}`},
+		{"GET", "/audited/1", "carol", nil, `{"id":1,"name":"Edited","_audit":{"created_by":"alice","updated_by":"bob"}}`},
+		{"GET", "/audited", "carol", nil, `[{"id":1,"name":"Edited","_audit":{"created_by":"alice","updated_by":"bob"}}]`},
+		{"POST", "/plain", "alice", url.Values{"name": {"Plain"}}, `{"id":1,"name":"Plain","created_by":"","updated_by":""}`},
+		{"POST", "/plain/1", "bob", url.Values{"name": {"Plain"}, "created_by": {"bob"}}, `{"id":1,"name":"Plain","created_by":"bob","updated_by":""}`},
+	}
+
+	s := New()
+	s.SetJWTVerifier(func(token string) (Claims, error) {
+		return Claims{"sub": token}, nil
+	})
+	s.Add(ActorResource{}, NewMapStore("audited"), WithAudit())
+	s.Add(ActorResource{}, NewMapStore("plain"))
+
+	for _, request := range requests {
+		req := httptest.NewRequest(request.Method, request.Path, strings.NewReader(request.Data.Encode()))
+		req.Header.Set("Authorization", "Bearer "+request.Actor)
+		if request.Data != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		if body := rec.Body.String(); body != request.Body {
+			t.Errorf("%s %s: expected body '%s', got '%s'", request.Method, request.Path, request.Body, body)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package reason
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Claims are the claims of a verified bearer token.
+type Claims map[string]interface{}
+
+// SetJWTVerifier requires requests to carry an "Authorization: Bearer" token
+// that fn verifies, returning the token's claims. Requests without a token or
+// with a token fn rejects receive http.StatusUnauthorized. The claims of
+// verified tokens are stored in the request context, see ClaimsFromContext.
+// Resources added WithPublic can be requested without a token.
+func (s *Server) SetJWTVerifier(fn func(token string) (Claims, error)) {
+	s.jwtVerifier = fn
+}
+
+// WithPublic allows requests for the resource without a bearer token when the
+// server verifies tokens. Tokens that are sent are still verified.
+func WithPublic() ResourceOption {
+	return func(reg *registration) {
+		reg.public = true
+	}
+}
+
+// ClaimsFromContext returns the claims of the bearer token a request was
+// authenticated with, or nil for requests without a token.
+func ClaimsFromContext(ctx context.Context) Claims {
+	claims, _ := ctx.Value(claimsKey).(Claims)
+	return claims
+}
+
+// authenticate verifies the bearer token of a request, storing its claims in
+// the request context. It writes the response and returns false when the
+// request is not authorized.
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request, reg *registration) (*http.Request, bool) {
+	if s.jwtVerifier == nil {
+		return r, true
+	}
+
+	header := r.Header.Get("Authorization")
+	if header == "" && reg.public {
+		return r, true
+	}
+	if len(header) < len("Bearer ") || !strings.EqualFold(header[0:len("Bearer ")], "Bearer ") {
+		s.unauthorized(w, r, "Bearer token is required")
+		return r, false
+	}
+
+	claims, err := s.jwtVerifier(strings.TrimSpace(header[len("Bearer "):]))
+	if err != nil {
+		s.unauthorized(w, r, "Bearer token is invalid")
+		return r, false
+	}
+	return r.WithContext(context.WithValue(r.Context(), claimsKey, claims)), true
+}
+
+func (s *Server) unauthorized(w http.ResponseWriter, r *http.Request, message string) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	s.writeError(w, r, &APIError{
+		Status:  http.StatusUnauthorized,
+		Code:    "unauthorized",
+		Message: message,
+	})
+}
@@ -0,0 +1,94 @@
+package reason
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type ClaimsHandler struct {
+	TestResourceHandler
+}
+
+func (ch ClaimsHandler) Path() string {
+	return "claims"
+}
+
+func (ch ClaimsHandler) WithContext(ctx context.Context) ResourceHandler {
+	return claimsGetter{ClaimsFromContext(ctx)}
+}
+
+type claimsGetter struct {
+	claims Claims
+}
+
+func (cg claimsGetter) Path() string {
+	return "claims"
+}
+
+func (cg claimsGetter) GetResource(id string) (interface{}, error) {
+	return cg.claims, nil
+}
+
+func TestSetJWTVerifier(t *testing.T) {
+	var requests = []struct {
+		Path          string
+		Authorization string
+		StatusCode    int
+		Body          string
+	}{
+		{"/claims/1", "", 401, `{"error":{"status":401,"code":"unauthorized","message":"Bearer token is required"}}`},
+		{"/claims/1", "Basic abc", 401, `{"error":{"status":401,"code":"unauthorized","message":"Bearer token is required"}}`},
+		{"/claims/1", "Bearer bad", 401, `{"error":{"status":401,"code":"unauthorized","message":"Bearer token is invalid"}}`},
+		{"/claims/1", "Bearer good", 200, `{"sub":"jo"}`},
+		{"/claims/1", "bearer good", 200, `{"sub":"jo"}`},
+		{"/test/1", "", 200, `{"id":1,"name":"The Test"}`},
+		{"/test/1", "Bearer bad", 401, `{"error":{"status":401,"code":"unauthorized","message":"Bearer token is invalid"}}`},
+	}
+
+	s := New()
+	s.SetJWTVerifier(func(token string) (Claims, error) {
+		if token != "good" {
+			return nil, errors.New("invalid token")
+		}
+		return Claims{"sub": "jo"}, nil
+	})
+	s.Add(TestResource{}, ClaimsHandler{})
+	s.Add(TestResource{}, TestResourceHandler{}, WithPublic())
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		req, err := http.NewRequest("GET", ts.URL+request.Path, nil)
+		if err != nil {
+			t.Fatalf("%s: expected no error from http.NewRequest, got %s", request.Path, err.Error())
+		}
+		if request.Authorization != "" {
+			req.Header.Set("Authorization", request.Authorization)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: expected no error from request, got %s", request.Path, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s (%s): expected status code %d, got %d", request.Path, request.Authorization, request.StatusCode, res.StatusCode)
+		}
+		if res.StatusCode == 401 && res.Header.Get("WWW-Authenticate") != "Bearer" {
+			t.Errorf("%s (%s): expected WWW-Authenticate 'Bearer', got '%s'", request.Path, request.Authorization, res.Header.Get("WWW-Authenticate"))
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Path, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s (%s): expected body '%s', got '%s'", request.Path, request.Authorization, request.Body, body)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package reason
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// bulkBatchSize is the number of resources decoded from a bulk create body
+// before they are passed to the handler.
+const bulkBatchSize = 100
+
+// BulkCreator implementers can create resources from a JSON array posted to
+// the create route. The array is decoded as it is read and its elements are
+// passed to CreateResources in batches, so that memory use does not grow with
+// the size of the body. The response counts the resources created:
+//
+//	{"created":250}
+//
+// When a batch fails or the body is malformed, the response has the status of
+// the error and counts the resources created before it:
+//
+//	{"created":200,"error":{"status":400,"code":"invalid_body","message":"Malformed JSON body"}}
+//
+// With SetTransaction, the batches are created in a single transaction that is
+// rolled back on error, and no resources are counted as created.
+type BulkCreator interface {
+	Creator
+	CreateResources(resources []interface{}) error
+}
+
+// PartialBulkCreator implementers report the outcome of each resource of a
+// bulk create, so that some resources can be created while others fail. The
+// response is 207 Multi-Status with the outcome of each element of the array,
+// in order:
+//
+//	[{"status":201,"resource":{"id":1,"name":"a"}},{"status":409,"error":{"status":409,"code":"conflict"}}]
+//
+// Resources that fail validation are reported without being passed to
+// CreateResourcesPartial, which must return one result per resource. A
+// malformed body still fails the whole request as for a BulkCreator.
+type PartialBulkCreator interface {
+	BulkCreator
+	CreateResourcesPartial(resources []interface{}) []BulkItemResult
+}
+
+// BulkItemResult is the outcome of creating one resource of a bulk create:
+// the resource created, or the error it failed with.
+type BulkItemResult struct {
+	Resource interface{}
+	Err      error
+}
+
+type bulkItem struct {
+	Status   int         `json:"status"`
+	Resource interface{} `json:"resource,omitempty"`
+	Error    *APIError   `json:"error,omitempty"`
+}
+
+type bulkResult struct {
+	Created int       `json:"created"`
+	Error   *APIError `json:"error,omitempty"`
+}
+
+// isJSONArray reports whether the request body starts with a JSON array. The
+// body is read ahead up to its first token and restored for parsing.
+func isJSONArray(r *http.Request) bool {
+	if r.Body == nil || r.Body == http.NoBody {
+		return false
+	}
+	br := bufio.NewReader(r.Body)
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{br, r.Body}
+
+	for n := 1; ; n++ {
+		peek, err := br.Peek(n)
+		if err != nil {
+			return false
+		}
+		switch peek[n-1] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '[':
+			return true
+		}
+		return false
+	}
+}
+
+// WithBulkParser accepts bodies of mediaType, such as "text/csv", on the
+// create route of a BulkCreator. The body is parsed with fn into resources,
+// which are validated and created in batches as the elements of a JSON array
+// are, for data import endpoints. Bodies of other media types are parsed as
+// usual.
+func WithBulkParser(mediaType string, fn func(body io.Reader) ([]interface{}, error)) ResourceOption {
+	return func(reg *registration) {
+		if reg.bulkParsers == nil {
+			reg.bulkParsers = make(map[string]func(io.Reader) ([]interface{}, error))
+		}
+		reg.bulkParsers[strings.ToLower(mediaType)] = fn
+	}
+}
+
+// bulkParser returns the function parsing the body of a bulk create into a
+// batcher, or false when the request is not a bulk create.
+func (s *Server) bulkParser(r *http.Request, reg *registration) (func(r *http.Request, b *batcher) error, bool) {
+	if isJSON(r) {
+		return s.decodeJSONArray, isJSONArray(r)
+	}
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, false
+	}
+	fn, ok := reg.bulkParsers[mediaType]
+	if !ok {
+		return nil, false
+	}
+	return func(r *http.Request, b *batcher) error {
+		resources, err := fn(r.Body)
+		if err != nil {
+			return err
+		}
+		for _, data := range resources {
+			if err := b.add(data); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, true
+}
+
+func (s *Server) bulkCreateRequest(w http.ResponseWriter, r *http.Request, reg *registration, creator BulkCreator, parse func(r *http.Request, b *batcher) error) {
+	s.limitBody(w, r)
+
+	b := &batcher{s: s, r: r, reg: reg, creator: creator}
+	if partial, ok := creator.(PartialBulkCreator); ok {
+		b.partial = partial
+		b.items = []bulkItem{}
+	}
+	err := parse(r, b)
+	if err == nil {
+		err = b.flush()
+	}
+	created := b.created
+	if clientGone(r, err) {
+		return
+	}
+	if err == nil && b.partial != nil {
+		s.writeResource(w, r, http.StatusMultiStatus, b.items)
+		return
+	}
+	if err == nil {
+		s.writeResource(w, r, http.StatusCreated, bulkResult{Created: created})
+		return
+	}
+
+	status := s.errorStatus(r, err)
+	if inTransaction(r) {
+		created = 0
+	}
+	result := bulkResult{Created: created}
+	if apiErr, ok := asAPIError(err); ok {
+		result.Error = new(APIError)
+		*result.Error = *apiErr
+		result.Error.Status = status
+	}
+	s.writeResource(w, r, status, result)
+}
+
+// batcher creates the resources of a bulk create in batches.
+type batcher struct {
+	s       *Server
+	r       *http.Request
+	reg     *registration
+	creator BulkCreator
+	batch   []interface{}
+	created int
+
+	// With a PartialBulkCreator, items holds the outcome of each resource
+	// and pending the index in items of each resource in the batch.
+	partial PartialBulkCreator
+	items   []bulkItem
+	pending []int
+}
+
+// add prepares a parsed resource as a single create would and adds it to the
+// batch, creating the batch once it is full.
+func (b *batcher) add(data interface{}) error {
+	s, r, reg := b.s, b.r, b.reg
+	data, err := s.applyFieldRules(reg, data)
+	if err == nil {
+		data, err = s.generateID(reg, data)
+	}
+	if err == nil {
+		data, err = s.stampTimestamps(reg, data, OperationCreate)
+	}
+	if err == nil {
+		data, err = s.stampActors(r, reg, data, OperationCreate)
+	}
+	if err == nil {
+		err = validate(r, b.creator, data, OperationCreate)
+	}
+	if err != nil && b.partial != nil {
+		b.items = append(b.items, b.itemError(err))
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if b.partial != nil {
+		b.pending = append(b.pending, len(b.items))
+		b.items = append(b.items, bulkItem{})
+	}
+	b.batch = append(b.batch, data)
+	if len(b.batch) == bulkBatchSize {
+		return b.flush()
+	}
+	return nil
+}
+
+// flush creates the resources in the batch.
+func (b *batcher) flush() error {
+	if len(b.batch) == 0 {
+		return nil
+	}
+	if b.reg.cancelled(b.r) {
+		return b.r.Context().Err()
+	}
+	if b.partial != nil {
+		if err := b.flushPartial(); err != nil {
+			return err
+		}
+	} else if err := b.creator.CreateResources(b.batch); err != nil {
+		return err
+	} else {
+		b.created += len(b.batch)
+	}
+	b.batch = make([]interface{}, 0, bulkBatchSize)
+	return nil
+}
+
+// flushPartial creates the resources in the batch with a PartialBulkCreator,
+// recording the outcome of each.
+func (b *batcher) flushPartial() error {
+	results := b.partial.CreateResourcesPartial(b.batch)
+	if len(results) != len(b.batch) {
+		return fmt.Errorf("reason: CreateResourcesPartial returned %d results for %d resources", len(results), len(b.batch))
+	}
+
+	for k, result := range results {
+		item := &b.items[b.pending[k]]
+		if result.Err != nil {
+			*item = b.itemError(result.Err)
+			continue
+		}
+		rep, err := b.s.represent(b.reg, b.creator, result.Resource)
+		if err != nil {
+			return err
+		}
+		if rep, err = authorizeFields(b.r, b.reg, b.creator, result.Resource, rep); err != nil {
+			return err
+		}
+		*item = bulkItem{Status: http.StatusCreated, Resource: rep}
+		b.created++
+	}
+	b.pending = b.pending[:0]
+	return nil
+}
+
+// itemError returns the outcome of a resource that failed with err.
+func (b *batcher) itemError(err error) bulkItem {
+	item := bulkItem{Status: b.s.errorStatus(b.r, err)}
+	if apiErr, ok := asAPIError(err); ok {
+		item.Error = new(APIError)
+		*item.Error = *apiErr
+		item.Error.Status = item.Status
+	}
+	return item
+}
+
+// decodeJSONArray decodes the elements of the JSON array in the request body
+// into the batcher as they are read.
+func (s *Server) decodeJSONArray(r *http.Request, b *batcher) error {
+	dec := json.NewDecoder(r.Body)
+	if _, err := dec.Token(); err != nil {
+		return jsonError(err)
+	}
+
+	t := reflect.TypeOf(b.reg.schema)
+	for dec.More() {
+		val := reflect.New(t)
+		if err := dec.Decode(val.Interface()); err != nil {
+			return jsonError(err)
+		}
+		if err := b.add(val.Elem().Interface()); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return jsonError(err)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package reason
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type BulkHandler struct {
+	TestResourceHandler
+	batches *[]int
+}
+
+func (bh BulkHandler) Path() string {
+	return "bulk"
+}
+
+func (bh BulkHandler) CreateResources(resources []interface{}) error {
+	for _, res := range resources {
+		if res.(TestResource).Name == "fail" {
+			return &APIError{Status: http.StatusConflict, Code: "conflict", Err: errors.New("failed")}
+		}
+	}
+	*bh.batches = append(*bh.batches, len(resources))
+	return nil
+}
+
+func bulkBody(n int, last string) string {
+	items := make([]string, n)
+	for k := range items {
+		items[k] = fmt.Sprintf(`{"name":"item %d"}`, k)
+	}
+	if last != "" {
+		items = append(items, last)
+	}
+	return " [" + strings.Join(items, ",") + "]"
+}
+
+func TestBulkCreator(t *testing.T) {
+	var requests = []struct {
+		Body       string
+		StatusCode int
+		Response   string
+		Batches    []int
+	}{
+		{bulkBody(250, ""), 201, `{"created":250}`, []int{100, 100, 50}},
+		{bulkBody(0, ""), 201, `{"created":0}`, nil},
+		{bulkBody(150, `{"name":"fail"}`), 409, `{"created":100,"error":{"status":409,"code":"conflict"}}`, []int{100}},
+		{bulkBody(120, `{"name":`), 400, `{"created":100,"error":{"status":400,"code":"invalid_body","message":"Malformed JSON body"}}`, []int{100}},
+		{`{"name":"single"}`, 201, `{"id":3,"name":"single"}`, nil},
+	}
+
+	var batches []int
+	s := New()
+	s.Add(TestResource{}, BulkHandler{batches: &batches})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for k, request := range requests {
+		batches = nil
+		res, err := http.Post(ts.URL+"/bulk", "application/json", strings.NewReader(request.Body))
+		if err != nil {
+			t.Fatalf("%d: expected no error from request, got %s", k, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%d: expected status code %d, got %d", k, request.StatusCode, res.StatusCode)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%d: expected no error from read, got %s", k, err.Error())
+		}
+
+		if string(body) != request.Response {
+			t.Errorf("%d: expected body '%s', got '%s'", k, request.Response, body)
+		}
+		if fmt.Sprint(batches) != fmt.Sprint(request.Batches) {
+			t.Errorf("%d: expected batches %v, got %v", k, request.Batches, batches)
+		}
+	}
+}
+
+func parseCSV(body io.Reader) ([]interface{}, error) {
+	records, err := csv.NewReader(body).ReadAll()
+	if err != nil {
+		return nil, &APIError{Status: http.StatusBadRequest, Code: "invalid_body", Message: "Malformed CSV body", Err: err}
+	}
+	resources := make([]interface{}, len(records))
+	for k, record := range records {
+		resources[k] = TestResource{Name: record[0]}
+	}
+	return resources, nil
+}
+
+func TestBulkParser(t *testing.T) {
+	var requests = []struct {
+		ContentType string
+		Body        string
+		StatusCode  int
+		Response    string
+		Batches     []int
+	}{
+		{"text/csv", strings.Repeat("item\n", 150), 201, `{"created":150}`, []int{100, 50}},
+		{"text/csv; charset=utf-8", "a\nb\n", 201, `{"created":2}`, []int{2}},
+		{"text/csv", "a\nfail\n", 409, `{"created":0,"error":{"status":409,"code":"conflict"}}`, nil},
+		{"text/csv", "\"a\n", 400, `{"created":0,"error":{"status":400,"code":"invalid_body","message":"Malformed CSV body"}}`, nil},
+	}
+
+	var batches []int
+	s := New()
+	s.Add(TestResource{}, BulkHandler{batches: &batches}, WithBulkParser("text/csv", parseCSV))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for k, request := range requests {
+		batches = nil
+		res, err := http.Post(ts.URL+"/bulk", request.ContentType, strings.NewReader(request.Body))
+		if err != nil {
+			t.Fatalf("%d: expected no error from request, got %s", k, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%d: expected status code %d, got %d", k, request.StatusCode, res.StatusCode)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%d: expected no error from read, got %s", k, err.Error())
+		}
+
+		if string(body) != request.Response {
+			t.Errorf("%d: expected body '%s', got '%s'", k, request.Response, body)
+		}
+		if fmt.Sprint(batches) != fmt.Sprint(request.Batches) {
+			t.Errorf("%d: expected batches %v, got %v", k, request.Batches, batches)
+		}
+	}
+}
+
+type PartialBulkHandler struct {
+	BulkHandler
+}
+
+func (ph PartialBulkHandler) Path() string {
+	return "partial"
+}
+
+func (ph PartialBulkHandler) Validate(ctx context.Context, data interface{}, op Operation) error {
+	if data.(TestResource).Name == "" {
+		return &ValidationError{Field: "name", Message: "Name is required"}
+	}
+	return nil
+}
+
+func (ph PartialBulkHandler) CreateResourcesPartial(resources []interface{}) []BulkItemResult {
+	*ph.batches = append(*ph.batches, len(resources))
+	results := make([]BulkItemResult, len(resources))
+	for k, res := range resources {
+		tr := res.(TestResource)
+		switch tr.Name {
+		case "fail":
+			results[k].Err = &APIError{Status: http.StatusConflict, Code: "conflict", Err: errors.New("failed")}
+		case "error":
+			results[k].Err = errors.New("failed")
+		default:
+			tr.ID = int64(k + 1)
+			results[k].Resource = tr
+		}
+	}
+	return results
+}
+
+func TestPartialBulkCreator(t *testing.T) {
+	var requests = []struct {
+		Body       string
+		StatusCode int
+		Response   string
+		Batches    []int
+	}{
+		{`[{"name":"a"},{"name":"fail"},{"name":""},{"name":"error"},{"name":"b"}]`, 207, `[{"status":201,"resource":{"id":1,"name":"a"}},{"status":409,"error":{"status":409,"code":"conflict"}},{"status":422,"error":{"status":422,"code":"invalid","message":"Name is required","field":"name"}},{"status":500},{"status":201,"resource":{"id":4,"name":"b"}}]`, []int{4}},
+		{`[]`, 207, `[]`, nil},
+		{`[{"name":"a"},{"name":`, 400, `{"created":0,"error":{"status":400,"code":"invalid_body","message":"Malformed JSON body"}}`, nil},
+	}
+
+	var batches []int
+	s := New(WithLogger(log.New(ioutil.Discard, "", 0)))
+	s.Add(TestResource{}, PartialBulkHandler{BulkHandler{batches: &batches}})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for k, request := range requests {
+		batches = nil
+		res, err := http.Post(ts.URL+"/partial", "application/json", strings.NewReader(request.Body))
+		if err != nil {
+			t.Fatalf("%d: expected no error from request, got %s", k, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%d: expected status code %d, got %d", k, request.StatusCode, res.StatusCode)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%d: expected no error from read, got %s", k, err.Error())
+		}
+
+		if string(body) != request.Response {
+			t.Errorf("%d: expected body '%s', got '%s'", k, request.Response, body)
+		}
+		if fmt.Sprint(batches) != fmt.Sprint(request.Batches) {
+			t.Errorf("%d: expected batches %v, got %v", k, request.Batches, batches)
+		}
+	}
+}
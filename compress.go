@@ -0,0 +1,185 @@
+package reason
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type contentCoding struct {
+	token      string
+	newEncoder func(w io.Writer) io.WriteCloser
+}
+
+// SetCompression enables compressing responses with the content coding
+// negotiated from the Accept-Encoding header. gzip and deflate are supported,
+// more codings can be added with RegisterEncoding. Clients that send no
+// Accept-Encoding header or prefer identity receive uncompressed responses.
+func (s *Server) SetCompression(enabled bool) {
+	s.compression = enabled
+}
+
+// RegisterEncoding adds a content coding that responses can be compressed
+// with, such as "br", replacing any coding registered with the same token.
+// When clients accept several codings with the same preference, the coding
+// registered first is used.
+func (s *Server) RegisterEncoding(token string, newEncoder func(w io.Writer) io.WriteCloser) {
+	token = strings.ToLower(token)
+	for k, enc := range s.encodings {
+		if enc.token == token {
+			s.encodings[k].newEncoder = newEncoder
+			return
+		}
+	}
+	s.encodings = append(s.encodings, contentCoding{token, newEncoder})
+}
+
+func defaultEncodings() []contentCoding {
+	return []contentCoding{
+		{"gzip", func(w io.Writer) io.WriteCloser {
+			return gzip.NewWriter(w)
+		}},
+		{"deflate", func(w io.Writer) io.WriteCloser {
+			fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return fw
+		}},
+	}
+}
+
+// negotiateEncoding picks the registered coding most preferred by an
+// Accept-Encoding header, or returns false to leave the response uncompressed.
+func negotiateEncoding(header string, encodings []contentCoding) (contentCoding, bool) {
+	qs := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		token, q := part, 1.0
+		if idx := strings.Index(part, ";"); idx != -1 {
+			token = part[0:idx]
+			param := strings.TrimSpace(part[idx+1:])
+			if strings.HasPrefix(param, "q=") {
+				var err error
+				if q, err = strconv.ParseFloat(param[2:], 64); err != nil {
+					continue
+				}
+			}
+		}
+		if token = strings.ToLower(strings.TrimSpace(token)); token != "" {
+			qs[token] = q
+		}
+	}
+
+	type candidate struct {
+		enc contentCoding
+		q   float64
+	}
+	var candidates []candidate
+	for _, enc := range encodings {
+		q, ok := qs[enc.token]
+		if !ok {
+			q, ok = qs["*"]
+		}
+		if ok && q > 0 {
+			candidates = append(candidates, candidate{enc, q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+
+	if len(candidates) == 0 {
+		return contentCoding{}, false
+	}
+	if q, ok := qs["identity"]; ok && q > candidates[0].q {
+		return contentCoding{}, false
+	}
+	return candidates[0].enc, true
+}
+
+// compressWriter compresses the response body. The status is held back until
+// the first write so that responses without a body are left uncompressed.
+// Partial content is never compressed, as its range refers to the
+// uncompressed body.
+type compressWriter struct {
+	http.ResponseWriter
+	enc     contentCoding
+	encoder io.WriteCloser
+	status  int
+	written bool
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.status == 0 {
+		cw.status = status
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.written {
+		cw.written = true
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+
+		h := cw.Header()
+		if h.Get("Content-Encoding") == "" && cw.status != http.StatusNoContent && cw.status != http.StatusNotModified && cw.status != http.StatusPartialContent {
+			h.Set("Content-Encoding", cw.enc.token)
+			h.Del("Content-Length")
+			cw.encoder = cw.enc.newEncoder(cw.ResponseWriter)
+		}
+		cw.ResponseWriter.WriteHeader(cw.status)
+	}
+
+	if cw.encoder == nil {
+		return cw.ResponseWriter.Write(b)
+	}
+	return cw.encoder.Write(b)
+}
+
+// Flush flushes the compressed data written so far to the client.
+func (cw *compressWriter) Flush() {
+	if flusher, ok := cw.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close completes the response, writing the held back status if nothing was
+// written.
+func (cw *compressWriter) Close() error {
+	if !cw.written {
+		cw.written = true
+		if cw.status != 0 {
+			cw.ResponseWriter.WriteHeader(cw.status)
+		}
+	}
+	if cw.encoder != nil {
+		return cw.encoder.Close()
+	}
+	return nil
+}
+
+// compress wraps w to compress the response when compression is enabled and
+// the client accepts a registered coding. The compressed body of a HEAD
+// request is discarded, leaving only the headers.
+func (s *Server) compress(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, func()) {
+	if !s.compression {
+		return w, func() {}
+	}
+
+	w.Header().Add("Vary", "Accept-Encoding")
+	enc, ok := negotiateEncoding(r.Header.Get("Accept-Encoding"), s.encodings)
+	if !ok {
+		return w, func() {}
+	}
+
+	cw := &compressWriter{ResponseWriter: w, enc: enc}
+	if r.Method == http.MethodHead {
+		cw.ResponseWriter = &headWriter{ResponseWriter: w}
+	}
+	return cw, func() { cw.Close() }
+}
@@ -0,0 +1,106 @@
+package reason
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+	var requests = []struct {
+		AcceptEncoding  string
+		ContentEncoding string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"deflate", "deflate"},
+		{"gzip;q=0.5, deflate", "deflate"},
+		{"deflate, gzip", "gzip"},
+		{"br;q=1.0, gzip;q=0.8", "gzip"},
+		{"gzip;q=0, deflate;q=0", ""},
+		{"*", "gzip"},
+		{"*, gzip;q=0", "deflate"},
+		{"gzip;q=0.5, identity", ""},
+	}
+
+	s := New()
+	s.SetCompression(true)
+	s.Add(TestResource{}, TestResourceHandler{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	transport := &http.Transport{DisableCompression: true}
+	client := &http.Client{Transport: transport}
+
+	for _, request := range requests {
+		req, err := http.NewRequest("GET", ts.URL+"/test/1", nil)
+		if err != nil {
+			t.Fatalf("%s: expected no error from http.NewRequest, got %s", request.AcceptEncoding, err.Error())
+		}
+		if request.AcceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", request.AcceptEncoding)
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%s: expected no error from request, got %s", request.AcceptEncoding, err.Error())
+		}
+
+		if res.Header.Get("Content-Encoding") != request.ContentEncoding {
+			t.Errorf("%s: expected Content-Encoding '%s', got '%s'", request.AcceptEncoding, request.ContentEncoding, res.Header.Get("Content-Encoding"))
+		}
+		if res.Header.Get("Vary") != "Accept-Encoding" {
+			t.Errorf("%s: expected Vary 'Accept-Encoding', got '%s'", request.AcceptEncoding, res.Header.Get("Vary"))
+		}
+
+		var r io.Reader = res.Body
+		switch request.ContentEncoding {
+		case "gzip":
+			r, err = gzip.NewReader(res.Body)
+			if err != nil {
+				t.Fatalf("%s: expected no error from gzip.NewReader, got %s", request.AcceptEncoding, err.Error())
+			}
+		case "deflate":
+			r = flate.NewReader(res.Body)
+		}
+		body, err := ioutil.ReadAll(r)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.AcceptEncoding, err.Error())
+		}
+
+		if string(body) != `{"id":1,"name":"The Test"}` {
+			t.Errorf("%s: expected body '%s', got '%s'", request.AcceptEncoding, `{"id":1,"name":"The Test"}`, body)
+		}
+	}
+}
+
+func TestRegisterEncoding(t *testing.T) {
+	s := New()
+	s.SetCompression(true)
+	s.RegisterEncoding("BR", func(w io.Writer) io.WriteCloser {
+		return gzip.NewWriter(w)
+	})
+	s.Add(TestResource{}, TestResourceHandler{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	req, err := http.NewRequest("GET", ts.URL+"/test/1", nil)
+	if err != nil {
+		t.Fatalf("expected no error from http.NewRequest, got %s", err.Error())
+	}
+	req.Header.Set("Accept-Encoding", "br, gzip;q=0.8")
+	res, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("expected no error from request, got %s", err.Error())
+	}
+	res.Body.Close()
+
+	if res.Header.Get("Content-Encoding") != "br" {
+		t.Errorf("expected Content-Encoding 'br', got '%s'", res.Header.Get("Content-Encoding"))
+	}
+}
@@ -0,0 +1,37 @@
+package reason
+
+import "sort"
+
+// Computed implementers can add fields that are not part of the resource
+// struct to its JSON representation, such as a full_name built from first and
+// last names. Computed fields replace struct fields with the same name.
+type Computed interface {
+	ComputedFields(resource interface{}) map[string]interface{}
+}
+
+// withComputedFields adds the computed fields of a resource to its JSON
+// representation.
+func withComputedFields(res interface{}, computed Computed) (interface{}, error) {
+	fields := computed.ComputedFields(res)
+	if len(fields) == 0 {
+		return res, nil
+	}
+
+	obj, err := newJSONObject(res)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add fields in a stable order
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := obj.Set(key, fields[key]); err != nil {
+			return nil, err
+		}
+	}
+	return obj, nil
+}
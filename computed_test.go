@@ -0,0 +1,80 @@
+package reason
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type ComputedHandler struct {
+	TestResourceHandler
+}
+
+func (ch ComputedHandler) Path() string {
+	return "computed"
+}
+
+func (ch ComputedHandler) ComputedFields(resource interface{}) map[string]interface{} {
+	tr := resource.(TestResource)
+	if tr.ID == 2 {
+		return nil
+	}
+	return map[string]interface{}{
+		"upper_name": strings.ToUpper(tr.Name),
+		"id":         "test-" + strings.Repeat("1", int(tr.ID)),
+	}
+}
+
+func TestComputed(t *testing.T) {
+	form := url.Values{}
+	form.Add("name", "New Test")
+
+	var requests = []struct {
+		Path       string
+		StatusCode int
+		Body       string
+		Data       url.Values
+	}{
+		{"/computed/1", 200, `{"id":"test-1","name":"The Test","upper_name":"THE TEST"}`, nil},
+		{"/computed/2", 200, `{"id":2,"name":"The Other"}`, nil},
+		{"/computed", 200, `[{"id":"test-1","name":"The Test","upper_name":"THE TEST"},{"id":2,"name":"The Other"}]`, nil},
+		{"/computed", 201, `{"id":"test-111","name":"New Test","upper_name":"NEW TEST"}`, form},
+		{"/test/1", 200, `{"id":1,"name":"The Test"}`, nil},
+	}
+
+	s := New()
+	s.Add(TestResource{}, ComputedHandler{})
+	s.Add(TestResource{}, TestResourceHandler{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		var res *http.Response
+		var err error
+		if request.Data != nil {
+			res, err = http.PostForm(ts.URL+request.Path, request.Data)
+		} else {
+			res, err = http.Get(ts.URL + request.Path)
+		}
+		if err != nil {
+			t.Errorf("%s: expected no error from request, got %s", request.Path, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Path, request.StatusCode, res.StatusCode)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Path, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Path, request.Body, body)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package reason
+
+import "net/http"
+
+// SetResourceConcurrency limits the number of requests served concurrently by
+// the resource at path, such as "users" or "v2/users" for a resource added
+// with WithVersion("v2"). Requests over the limit wait for a slot until their
+// context's deadline, requests without a deadline are rejected immediately.
+// Rejected requests receive http.StatusServiceUnavailable. A max of 0 removes
+// the limit.
+func (s *Server) SetResourceConcurrency(path string, max int) {
+	if max <= 0 {
+		delete(s.concurrency, path)
+		return
+	}
+	s.concurrency[path] = make(chan struct{}, max)
+}
+
+// acquireResource takes a slot from the resource's concurrency limit,
+// returning the semaphore the slot must be released to. It writes the response
+// and returns false when no slot is available in time.
+func (s *Server) acquireResource(w http.ResponseWriter, r *http.Request, reg *registration) (chan struct{}, bool) {
+	sem, ok := s.concurrency[reg.path]
+	if !ok {
+		return nil, true
+	}
+
+	select {
+	case sem <- struct{}{}:
+		return sem, true
+	default:
+	}
+
+	if _, ok := r.Context().Deadline(); ok {
+		select {
+		case sem <- struct{}{}:
+			return sem, true
+		case <-r.Context().Done():
+		}
+	}
+
+	w.Header().Set("Retry-After", "1")
+	s.writeError(w, r, &APIError{
+		Status:  http.StatusServiceUnavailable,
+		Code:    "overloaded",
+		Message: "Too many concurrent requests",
+	})
+	return nil, false
+}
@@ -0,0 +1,75 @@
+package reason
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestResourceConcurrency(t *testing.T) {
+	handler := BlockingHandler{make(chan struct{}), make(chan struct{})}
+
+	s := New()
+	s.Add(TestResource{}, handler)
+	s.Add(TestResource{}, TestResourceHandler{})
+	s.SetResourceConcurrency("block", 2)
+
+	done := make(chan int)
+	for i := 0; i < 2; i++ {
+		go func() {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest("GET", "/block/1", nil))
+			done <- w.Code
+		}()
+		<-handler.started
+	}
+
+	// Without a deadline the request is rejected immediately
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/block/1", nil))
+	if w.Code != 503 {
+		t.Errorf("expected status code 503 over the limit, got %d", w.Code)
+	}
+	if body := w.Body.String(); body != `{"error":{"status":503,"code":"overloaded","message":"Too many concurrent requests"}}` {
+		t.Errorf("expected an overloaded error body, got '%s'", body)
+	}
+
+	// With a deadline the request waits for a slot
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/block/1", nil).WithContext(ctx))
+	cancel()
+	if w.Code != 503 {
+		t.Errorf("expected status code 503 after the deadline, got %d", w.Code)
+	}
+
+	// Other resources are not limited
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/test/1", nil))
+	if w.Code != 200 {
+		t.Errorf("expected status code 200 for another resource, got %d", w.Code)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	queued := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/block/1", nil).WithContext(ctx))
+		queued <- w.Code
+	}()
+
+	handler.release <- struct{}{}
+	if code := <-done; code != 200 {
+		t.Errorf("expected status code 200, got %d", code)
+	}
+	<-handler.started
+	close(handler.release)
+	if code := <-done; code != 200 {
+		t.Errorf("expected status code 200, got %d", code)
+	}
+	if code := <-queued; code != 200 {
+		t.Errorf("expected queued request to complete with 200, got %d", code)
+	}
+}
@@ -0,0 +1,133 @@
+package reason
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// contextKey is the type of the request context keys set by the server.
+type contextKey int
+
+const (
+	languageKey contextKey = iota
+	pathKey
+	operationKey
+	formatKey
+	claimsKey
+	flagsKey
+	requestIDKey
+	transactionKey
+	storeKey
+	routeKey
+	observedRouteKey
+)
+
+// ContextHandler implementers are bound to the context of each request before
+// it is served. WithContext should return a copy of the handler holding ctx,
+// implementing the same interfaces, so that the handler can use the request
+// context for cancellation and the values stored by the server, such as
+// PathFromContext.
+type ContextHandler interface {
+	WithContext(ctx context.Context) ResourceHandler
+}
+
+// PathFromContext returns the path of the resource serving a request, such as
+// "users" or, for a resource added with WithVersion("v2"), "v2/users".
+func PathFromContext(ctx context.Context) string {
+	path, _ := ctx.Value(pathKey).(string)
+	return path
+}
+
+// RouteFromContext returns the template of the route serving a request, such
+// as "/users/:id", which unlike the request path has a bounded number of
+// values, as metrics labels need.
+func RouteFromContext(ctx context.Context) string {
+	route, _ := ctx.Value(routeKey).(string)
+	return route
+}
+
+// OperationFromContext returns the operation a request is serving.
+func OperationFromContext(ctx context.Context) Operation {
+	op, _ := ctx.Value(operationKey).(Operation)
+	return op
+}
+
+// route wraps the handle for a resource operation served at the route
+// template, storing the resource path, operation and template in the request
+// context. Requests for ids not matching the resource's id pattern are not
+// found.
+func (s *Server) route(reg *registration, op Operation, template string, fn httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if observed, ok := r.Context().Value(observedRouteKey).(*string); ok {
+			*observed = template
+		}
+
+		if reg.idPattern != nil {
+			if id := ps.ByName(reg.param); id != "" && !reg.idPattern.MatchString(id) {
+				s.notFound(w, r)
+				return
+			}
+		}
+		reg.setDeprecationHeaders(w)
+
+		r, ok := s.authenticate(w, r, reg)
+		if !ok {
+			return
+		}
+
+		sem, ok := s.acquireResource(w, r, reg)
+		if !ok {
+			return
+		}
+		if sem != nil {
+			defer func() { <-sem }()
+		}
+
+		if s.bodyLogLimit > 0 {
+			var log *bodyLog
+			w, r, log = s.captureBodies(w, r)
+			defer s.logBodies(r, reg, log)
+		}
+
+		if len(s.formats(reg)) > 1 {
+			w.Header().Add("Vary", "Accept")
+		}
+		mediaType, ok := s.negotiateFormat(r, reg)
+		if !ok {
+			s.writeError(w, r, s.notAcceptable(reg))
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), pathKey, reg.path)
+		ctx = context.WithValue(ctx, operationKey, op)
+		ctx = context.WithValue(ctx, routeKey, template)
+		ctx = context.WithValue(ctx, formatKey, mediaType)
+		if s.store != nil {
+			ctx = context.WithValue(ctx, storeKey, s.store)
+		}
+		fn(w, r.WithContext(ctx), ps)
+	}
+}
+
+// cancelled reports whether the request context is done and the resource
+// handler implements ContextHandler, in which case a create or update is
+// skipped, as the client has gone away. Other handlers are not aware of the
+// request and always complete the operation.
+func (reg *registration) cancelled(r *http.Request) bool {
+	_, ok := reg.handler.(ContextHandler)
+	return ok && r.Context().Err() != nil
+}
+
+// bind returns the handler bound to the request context when it implements
+// ContextHandler. The handler is used as is when the bound handler does not
+// implement the operation's interface.
+func bind[T any](handler T, r *http.Request) T {
+	if ch, ok := any(handler).(ContextHandler); ok {
+		if bound, ok := ch.WithContext(r.Context()).(T); ok {
+			return bound
+		}
+	}
+	return handler
+}
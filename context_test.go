@@ -0,0 +1,144 @@
+package reason
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type ContextTestHandler struct {
+	ctx context.Context
+}
+
+func (ch ContextTestHandler) Path() string {
+	return "context"
+}
+
+func (ch ContextTestHandler) WithContext(ctx context.Context) ResourceHandler {
+	ch.ctx = ctx
+	return ch
+}
+
+func (ch ContextTestHandler) describe() string {
+	if ch.ctx == nil {
+		return "unbound"
+	}
+	return PathFromContext(ch.ctx) + " " + string(OperationFromContext(ch.ctx))
+}
+
+func (ch ContextTestHandler) GetResource(id string) (interface{}, error) {
+	return TestResource{1, ch.describe()}, nil
+}
+
+func (ch ContextTestHandler) ListResource() ([]interface{}, error) {
+	return []interface{}{TestResource{1, ch.describe()}}, nil
+}
+
+func (ch ContextTestHandler) CreateResource(resource interface{}) (interface{}, error) {
+	return TestResource{3, ch.describe()}, nil
+}
+
+func TestContextHandler(t *testing.T) {
+	var requests = []struct {
+		Path string
+		Body string
+		Data url.Values
+	}{
+		{"/context/1", `{"id":1,"name":"context get"}`, nil},
+		{"/context", `[{"id":1,"name":"context list"}]`, nil},
+		{"/context", `{"id":3,"name":"context create"}`, url.Values{}},
+		{"/v2/context/1", `{"id":1,"name":"v2/context get"}`, nil},
+	}
+
+	s := New()
+	s.Add(TestResource{}, ContextTestHandler{})
+	s.Add(TestResource{}, ContextTestHandler{}, WithVersion("v2"))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		var res *http.Response
+		var err error
+		if request.Data != nil {
+			res, err = http.PostForm(ts.URL+request.Path, request.Data)
+		} else {
+			res, err = http.Get(ts.URL + request.Path)
+		}
+		if err != nil {
+			t.Errorf("%s: expected no error from request, got %s", request.Path, err.Error())
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Path, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Path, request.Body, body)
+		}
+	}
+}
+
+type CancelAwareHandler struct {
+	ctx     context.Context
+	created *int
+}
+
+func (ch CancelAwareHandler) Path() string {
+	return "aware"
+}
+
+func (ch CancelAwareHandler) WithContext(ctx context.Context) ResourceHandler {
+	ch.ctx = ctx
+	return ch
+}
+
+func (ch CancelAwareHandler) GetResource(id string) (interface{}, error) {
+	return nil, ch.ctx.Err()
+}
+
+func (ch CancelAwareHandler) CreateResource(resource interface{}) (interface{}, error) {
+	*ch.created++
+	return resource, nil
+}
+
+func TestCancelledCreate(t *testing.T) {
+	var logs bytes.Buffer
+	var created int
+	s := New(WithLogger(log.New(&logs, "", 0)))
+	s.Add(TestResource{}, CancelAwareHandler{created: &created})
+	s.Add(TestResource{}, NewMapStore("unaware"))
+
+	for _, path := range []string{"/aware", "/unaware", "/aware/1"} {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		method := "POST"
+		if path == "/aware/1" {
+			method = "GET"
+		}
+		req := httptest.NewRequest(method, path, strings.NewReader("name=Gone"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req.WithContext(ctx))
+	}
+
+	if created != 0 {
+		t.Errorf("expected create to be skipped for a cancelled request, got %d creates", created)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/unaware/1", nil))
+	if rec.Code != 200 {
+		t.Errorf("expected create to complete for a handler unaware of the context, got status code %d", rec.Code)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("expected nothing logged for cancelled requests, got '%s'", logs.String())
+	}
+}
@@ -0,0 +1,20 @@
+package reason
+
+import "context"
+
+// SetStore stores v, such as a database handle or a container of the
+// dependencies of handlers, in the context of every resource request, so that
+// handlers implementing ContextHandler can be stateless and use the store the
+// server was configured with, see StoreFromContext. Tests can then serve the
+// same handlers with a fake store.
+func (s *Server) SetStore(v interface{}) {
+	s.store = v
+}
+
+// StoreFromContext returns the store set with SetStore, or nil when the
+// server has none. Handlers assert it to the type they were set up with:
+//
+//	db := reason.StoreFromContext(ctx).(*sql.DB)
+func StoreFromContext(ctx context.Context) interface{} {
+	return ctx.Value(storeKey)
+}
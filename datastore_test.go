@@ -0,0 +1,59 @@
+package reason
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+type StoreHandler struct {
+	ctx context.Context
+}
+
+func (sh StoreHandler) Path() string {
+	return "stored"
+}
+
+func (sh StoreHandler) WithContext(ctx context.Context) ResourceHandler {
+	sh.ctx = ctx
+	return sh
+}
+
+func (sh StoreHandler) GetResource(id string) (interface{}, error) {
+	store, ok := StoreFromContext(sh.ctx).(map[string]TestResource)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	res, ok := store[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return res, nil
+}
+
+func TestStoreFromContext(t *testing.T) {
+	var requests = []struct {
+		Store      interface{}
+		StatusCode int
+		Body       string
+	}{
+		{nil, 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`},
+		{map[string]TestResource{"1": {1, "Stored"}}, 200, `{"id":1,"name":"Stored"}`},
+	}
+
+	for _, request := range requests {
+		s := New()
+		s.SetStore(request.Store)
+		s.Add(TestResource{}, StoreHandler{})
+
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest("GET", "/stored/1", nil))
+
+		if rec.Code != request.StatusCode {
+			t.Errorf("%v: expected status code %d, got %d", request.Store, request.StatusCode, rec.Code)
+		}
+		if body := rec.Body.String(); body != request.Body {
+			t.Errorf("%v: expected body '%s', got '%s'", request.Store, request.Body, body)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package reason
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+)
+
+// redacted replaces the values of sensitive fields in logged bodies.
+const redacted = "[REDACTED]"
+
+// SetBodyLogging logs the request and response bodies of every resource
+// request to the server's logger, truncated to limit bytes each. Values of
+// schema fields tagged `reason:"sensitive"` are redacted, and bodies that
+// cannot be redacted because they are truncated or malformed are not logged.
+// Body logging is expensive and can leak data, it is meant for local debugging
+// only. A limit of 0, the default, disables it.
+func (s *Server) SetBodyLogging(limit int) {
+	s.bodyLogLimit = limit
+}
+
+// bodyLog holds the start of the request and response bodies of a request.
+type bodyLog struct {
+	limit    int
+	request  bytes.Buffer
+	response bytes.Buffer
+	reqSize  int
+	resSize  int
+	status   int
+}
+
+func (l *bodyLog) capture(buf *bytes.Buffer, b []byte) {
+	if n := l.limit - buf.Len(); n > 0 {
+		if len(b) > n {
+			b = b[:n]
+		}
+		buf.Write(b)
+	}
+}
+
+// captureReader records the body as the request handler reads it.
+type captureReader struct {
+	io.ReadCloser
+	log *bodyLog
+}
+
+func (cr *captureReader) Read(b []byte) (int, error) {
+	n, err := cr.ReadCloser.Read(b)
+	cr.log.capture(&cr.log.request, b[:n])
+	cr.log.reqSize += n
+	return n, err
+}
+
+// captureWriter records the status and body of the response.
+type captureWriter struct {
+	http.ResponseWriter
+	log *bodyLog
+}
+
+func (cw *captureWriter) WriteHeader(status int) {
+	if cw.log.status == 0 {
+		cw.log.status = status
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *captureWriter) Write(b []byte) (int, error) {
+	if cw.log.status == 0 {
+		cw.log.status = http.StatusOK
+	}
+	n, err := cw.ResponseWriter.Write(b)
+	cw.log.capture(&cw.log.response, b[:n])
+	cw.log.resSize += n
+	return n, err
+}
+
+func (cw *captureWriter) Flush() {
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// captureBodies wraps the request body and response writer to record the
+// bodies of a request for logBodies.
+func (s *Server) captureBodies(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *http.Request, *bodyLog) {
+	log := &bodyLog{limit: s.bodyLogLimit}
+	if r.Body != nil && r.Body != http.NoBody {
+		r.Body = &captureReader{r.Body, log}
+	}
+	return &captureWriter{w, log}, r, log
+}
+
+// logBodies logs the bodies recorded for a request.
+func (s *Server) logBodies(r *http.Request, reg *registration, log *bodyLog) {
+	sensitive := s.sensitiveFields(reg)
+	if log.reqSize > 0 {
+		s.logger.Printf("%s %s request body: %s", r.Method, r.URL.Path, redactBody(log.request.Bytes(), log.reqSize, sensitive))
+	}
+	s.logger.Printf("%s %s response %d body: %s", r.Method, r.URL.Path, log.status, redactBody(log.response.Bytes(), log.resSize, sensitive))
+}
+
+// sensitiveFields returns the JSON and form names of the schema fields tagged
+// as sensitive.
+func (s *Server) sensitiveFields(reg *registration) map[string]bool {
+	t := reflect.TypeOf(reg.schema)
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	names := make(map[string]bool)
+	for _, tag := range []string{"", s.tagName} {
+		fields, err := s.getSchemaFields(schemaKey{typ: t, tag: tag})
+		if err != nil {
+			continue
+		}
+		for _, field := range fields {
+			if field.sensitive {
+				names[field.name] = true
+			}
+		}
+	}
+	return names
+}
+
+// redactBody formats a logged body with its sensitive values redacted. Bodies
+// are redacted as JSON or, failing that, as a form.
+func redactBody(body []byte, size int, sensitive map[string]bool) string {
+	truncated := size > len(body)
+	if len(sensitive) == 0 {
+		if truncated {
+			return string(body) + "... (truncated)"
+		}
+		return string(body)
+	}
+	if truncated {
+		return "(truncated body not logged)"
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err == nil {
+		out, err := json.Marshal(redactJSON(v, sensitive))
+		if err == nil {
+			return string(out)
+		}
+	} else if form, err := url.ParseQuery(string(body)); err == nil {
+		for name := range form {
+			if sensitive[name] {
+				form.Set(name, redacted)
+			}
+		}
+		return form.Encode()
+	}
+	return "(malformed body not logged)"
+}
+
+// redactJSON replaces sensitive values in decoded JSON, in place.
+func redactJSON(v interface{}, sensitive map[string]bool) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			if sensitive[k] {
+				v[k] = redacted
+			} else {
+				v[k] = redactJSON(val, sensitive)
+			}
+		}
+	case []interface{}:
+		for k, val := range v {
+			v[k] = redactJSON(val, sensitive)
+		}
+	}
+	return v
+}
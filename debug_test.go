@@ -0,0 +1,76 @@
+package reason
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type AccountResource struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Password string `json:"password" reason:"sensitive"`
+}
+
+func TestBodyLogging(t *testing.T) {
+	var logs bytes.Buffer
+	s := New(WithLogger(log.New(&logs, "", 0)))
+	s.Add(AccountResource{}, NewMapStore("accounts"))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	form := url.Values{}
+	form.Add("name", "Jo")
+	form.Add("password", "hunter2")
+	res, err := http.PostForm(ts.URL+"/accounts", form)
+	if err != nil {
+		t.Fatalf("expected no error from request, got %s", err.Error())
+	}
+	res.Body.Close()
+	if logs.Len() != 0 {
+		t.Errorf("expected no logs with body logging disabled, got '%s'", logs.String())
+	}
+
+	var requests = []struct {
+		Limit int
+		Body  string
+		Logs  string
+	}{
+		{1024, `{"name":"Jo","password":"hunter2"}`, `POST /accounts request body: {"name":"Jo","password":"[REDACTED]"}
+POST /accounts response 201 body: {"id":2,"name":"Jo","password":"[REDACTED]"}
+`},
+		{8, `{"name":"Jo","password":"hunter2"}`, `POST /accounts request body: (truncated body not logged)
+POST /accounts response 201 body: (truncated body not logged)
+`},
+	}
+
+	for _, request := range requests {
+		logs.Reset()
+		s.SetBodyLogging(request.Limit)
+
+		res, err := http.Post(ts.URL+"/accounts", "application/json", strings.NewReader(request.Body))
+		if err != nil {
+			t.Fatalf("%d: expected no error from request, got %s", request.Limit, err.Error())
+		}
+		res.Body.Close()
+
+		if logs.String() != request.Logs {
+			t.Errorf("%d: expected logs '%s', got '%s'", request.Limit, request.Logs, logs.String())
+		}
+	}
+
+	logs.Reset()
+	s.SetBodyLogging(1024)
+	res, err = http.PostForm(ts.URL+"/accounts", form)
+	if err != nil {
+		t.Fatalf("expected no error from request, got %s", err.Error())
+	}
+	res.Body.Close()
+	if !strings.Contains(logs.String(), "request body: name=Jo&password=%5BREDACTED%5D\n") {
+		t.Errorf("expected redacted form body to be logged, got '%s'", logs.String())
+	}
+}
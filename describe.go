@@ -0,0 +1,104 @@
+package reason
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// SetDescribeOptions answers OPTIONS requests for resources with a JSON body
+// describing the resource along with the Allow header, for example:
+//
+//	{"path":"users","operations":["get","list","create"],"fields":[{"name":"id","type":"int64"},{"name":"email","type":"string"}],"filterable":["email"],"sortable":["id","email"]}
+//
+// OPTIONS requests are answered with an empty body by default. It must be set
+// before resources are added.
+func (s *Server) SetDescribeOptions(enabled bool) {
+	s.describeOptions = enabled
+}
+
+type resourceDescription struct {
+	Path       string             `json:"path"`
+	Operations []Operation        `json:"operations"`
+	Fields     []fieldDescription `json:"fields,omitempty"`
+	Filterable []string           `json:"filterable,omitempty"`
+	Sortable   []string           `json:"sortable,omitempty"`
+}
+
+type fieldDescription struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Required bool   `json:"required,omitempty"`
+}
+
+// addOptionsRoutes adds the OPTIONS routes describing the resource of reg.
+func (s *Server) addOptionsRoutes(router *httprouter.Router, reg *registration) {
+	var collection, item []string
+	var ops []Operation
+	if _, ok := reg.handler.(Getter); ok {
+		item = append(item, http.MethodGet, http.MethodHead)
+		ops = append(ops, OperationGet)
+	}
+	if _, ok := reg.handler.(Lister); ok {
+		collection = append(collection, http.MethodGet, http.MethodHead)
+		ops = append(ops, OperationList)
+	}
+	if _, ok := reg.handler.(Creator); ok {
+		collection = append(collection, http.MethodPost, http.MethodPut)
+		ops = append(ops, OperationCreate)
+	}
+	if _, ok := reg.handler.(Updater); ok {
+		item = append(item, http.MethodPost)
+		ops = append(ops, OperationUpdate)
+	}
+	if _, ok := reg.handler.(Replacer); ok {
+		item = append(item, http.MethodPut)
+		ops = append(ops, OperationReplace)
+	}
+	if _, ok := deleterFor(reg.handler); ok {
+		item = append(item, http.MethodDelete)
+		ops = append(ops, OperationDelete)
+	}
+
+	describe := func(allow []string) httprouter.Handle {
+		allow = append(allow, http.MethodOptions)
+		return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+			desc, err := s.describe(reg, ops)
+			if err != nil {
+				s.writeError(w, r, err)
+				return
+			}
+			w.Header().Set("Allow", strings.Join(allow, ", "))
+			s.writeResource(w, r, http.StatusOK, desc)
+		}
+	}
+	router.OPTIONS(reg.route, describe(collection))
+	router.OPTIONS(reg.route+"/:"+reg.param, describe(item))
+}
+
+// describe builds the description of the resource of reg from its schema
+// fields.
+func (s *Server) describe(reg *registration, ops []Operation) (*resourceDescription, error) {
+	desc := &resourceDescription{Path: reg.path, Operations: ops}
+
+	t := reflect.TypeOf(reg.schema)
+	if t == nil || t.Kind() != reflect.Struct {
+		return desc, nil
+	}
+	fields, err := s.getSchemaFields(schemaKey{t, reg, s.tagName})
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range fields {
+		desc.Fields = append(desc.Fields, fieldDescription{field.name, field.typ.String(), field.required})
+		if field.filterable {
+			desc.Filterable = append(desc.Filterable, field.name)
+		}
+		if field.sortable {
+			desc.Sortable = append(desc.Sortable, field.name)
+		}
+	}
+	return desc, nil
+}
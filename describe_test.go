@@ -0,0 +1,54 @@
+package reason
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDescribeOptions(t *testing.T) {
+	var requests = []struct {
+		Path  string
+		Allow string
+		Body  string
+	}{
+		{"/test", "GET, HEAD, POST, PUT, OPTIONS", `{"path":"test","operations":["get","list","create","update","delete"],"fields":[{"name":"id","type":"int64"},{"name":"name","type":"string"}]}`},
+		{"/test/1", "GET, HEAD, POST, DELETE, OPTIONS", `{"path":"test","operations":["get","list","create","update","delete"],"fields":[{"name":"id","type":"int64"},{"name":"name","type":"string"}]}`},
+		{"/query", "GET, HEAD, OPTIONS", `{"path":"query","operations":["list"],"fields":[{"name":"id","type":"int64"},{"name":"name","type":"string"},{"name":"secret","type":"string"}],"filterable":["name"],"sortable":["id","name"]}`},
+	}
+
+	s := New()
+	s.SetDescribeOptions(true)
+	s.Add(TestResource{}, TestResourceHandler{})
+	s.Add(QueryResource{}, QueryHandler{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		req, err := http.NewRequest("OPTIONS", ts.URL+request.Path, nil)
+		if err != nil {
+			t.Fatalf("%s: expected no error from http.NewRequest, got %s", request.Path, err.Error())
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: expected no error from request, got %s", request.Path, err.Error())
+		}
+
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("%s: expected status code 200, got %d", request.Path, res.StatusCode)
+		}
+		if allow := res.Header.Get("Allow"); allow != request.Allow {
+			t.Errorf("%s: expected Allow '%s', got '%s'", request.Path, request.Allow, allow)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Path, err.Error())
+		}
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Path, request.Body, body)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package reason
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"time"
+)
+
+// Download is a resource returned by GetResource to stream a file to the
+// client instead of a JSON representation. The body is sent as an attachment
+// named Filename. When Reader is an io.ReadSeeker, Range requests are served so
+// that downloads can be resumed. A Reader implementing io.Closer is closed once
+// the response is written.
+type Download struct {
+	Reader      io.Reader
+	Filename    string
+	ContentType string
+	ModTime     time.Time
+}
+
+// writeDownload streams a download to the client.
+func (s *Server) writeDownload(w http.ResponseWriter, r *http.Request, d *Download) {
+	if closer, ok := d.Reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	contentType := d.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	if d.Filename != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": d.Filename}))
+	} else {
+		w.Header().Set("Content-Disposition", "attachment")
+	}
+
+	if seeker, ok := d.Reader.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", d.ModTime, seeker)
+		return
+	}
+
+	w.Header().Set("Accept-Ranges", "none")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, d.Reader); err != nil {
+		s.logger.Printf("Failed to write download: %v", err)
+	}
+}
+
+// asDownload returns res as a Download if it is one.
+func asDownload(res interface{}) (*Download, bool) {
+	switch d := res.(type) {
+	case *Download:
+		return d, d != nil
+	case Download:
+		return &d, true
+	}
+	return nil, false
+}
@@ -0,0 +1,89 @@
+package reason
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type DownloadHandler struct{}
+
+func (dh DownloadHandler) Path() string {
+	return "files"
+}
+
+func (dh DownloadHandler) GetResource(id string) (interface{}, error) {
+	switch id {
+	case "report":
+		return &Download{
+			Reader:      bytes.NewReader([]byte("0123456789")),
+			Filename:    "report 1.txt",
+			ContentType: "text/plain",
+		}, nil
+	case "stream":
+		return Download{Reader: ioutil.NopCloser(strings.NewReader("streamed"))}, nil
+	}
+	return nil, ErrNotFound
+}
+
+func TestDownload(t *testing.T) {
+	var requests = []struct {
+		Path               string
+		Range              string
+		StatusCode         int
+		ContentType        string
+		ContentDisposition string
+		AcceptRanges       string
+		Body               string
+	}{
+		{"/files/report", "", 200, "text/plain", `attachment; filename="report 1.txt"`, "bytes", "0123456789"},
+		{"/files/report", "bytes=2-4", 206, "text/plain", `attachment; filename="report 1.txt"`, "bytes", "234"},
+		{"/files/stream", "", 200, "application/octet-stream", "attachment", "none", "streamed"},
+		{"/files/missing", "", 404, "application/json; charset=utf-8", "", "", `{"error":{"status":404,"code":"not_found","message":"Not found"}}`},
+	}
+
+	s := New()
+	s.Add(nil, DownloadHandler{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		req, err := http.NewRequest("GET", ts.URL+request.Path, nil)
+		if err != nil {
+			t.Fatalf("%s: expected no error from http.NewRequest, got %s", request.Path, err.Error())
+		}
+		if request.Range != "" {
+			req.Header.Set("Range", request.Range)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: expected no error from request, got %s", request.Path, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Path, request.StatusCode, res.StatusCode)
+		}
+		if contentType := res.Header.Get("Content-Type"); contentType != request.ContentType {
+			t.Errorf("%s: expected Content-Type '%s', got '%s'", request.Path, request.ContentType, contentType)
+		}
+		if disposition := res.Header.Get("Content-Disposition"); disposition != request.ContentDisposition {
+			t.Errorf("%s: expected Content-Disposition '%s', got '%s'", request.Path, request.ContentDisposition, disposition)
+		}
+		if acceptRanges := res.Header.Get("Accept-Ranges"); acceptRanges != request.AcceptRanges {
+			t.Errorf("%s: expected Accept-Ranges '%s', got '%s'", request.Path, request.AcceptRanges, acceptRanges)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Path, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Path, request.Body, body)
+		}
+	}
+}
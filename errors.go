@@ -0,0 +1,243 @@
+package reason
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// APIError is an error that is written to the client as a JSON body along with
+// its status code, for example:
+//
+//	{"error":{"status":409,"code":"conflict","message":"Name is taken","field":"name"}}
+//
+// Err may hold the underlying error, such as ErrConflict, which is used to
+// determine the status code when Status is not set. Type is a URI identifying
+// the kind of problem in problem+json responses, see SetProblemJSON. Resource
+// and ID name the resource an error is about, see SetDescriptiveNotFound.
+type APIError struct {
+	Status   int    `json:"status"`
+	Code     string `json:"code,omitempty"`
+	Message  string `json:"message,omitempty"`
+	Field    string `json:"field,omitempty"`
+	Resource string `json:"resource,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"-"`
+	Err      error  `json:"-"`
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return http.StatusText(e.Status)
+}
+
+// Unwrap returns the underlying error.
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode returns the status code the error is written with, so that
+// APIError implements HTTPError.
+func (e *APIError) StatusCode() int {
+	if e.Status != 0 {
+		return e.Status
+	}
+	return errorStatus(e.Err)
+}
+
+// NewConflict returns an error wrapping ErrConflict that names the field
+// responsible for the conflict in the response body.
+func NewConflict(field string, message string) error {
+	return &APIError{
+		Status:  http.StatusConflict,
+		Code:    "conflict",
+		Message: message,
+		Field:   field,
+		Err:     ErrConflict,
+	}
+}
+
+// NewHTTPError returns an error that responds with status and message, such
+// as NewHTTPError(http.StatusForbidden, "Not your document").
+func NewHTTPError(status int, message string) error {
+	return &APIError{Status: status, Message: message}
+}
+
+// HTTPError can be implemented by errors of other packages to choose the
+// status code they are written with. Their message is written as the message
+// of the error body, with the code of their status:
+//
+//	{"error":{"status":423,"code":"locked","message":"document locked"}}
+type HTTPError interface {
+	error
+	StatusCode() int
+}
+
+type errorBody struct {
+	Error *APIError `json:"error"`
+}
+
+// SetProblemJSON writes errors as RFC 7807 problem details with the
+// application/problem+json media type instead of the error envelope:
+//
+//	{"type":"about:blank","title":"Conflict","status":409,"detail":"Name is taken","instance":"/users","code":"conflict","field":"name"}
+//
+// Errors with no description beyond their status are written as problems too.
+func (s *Server) SetProblemJSON(enabled bool) {
+	s.problemJSON = enabled
+}
+
+// problem is an RFC 7807 problem details object, with the code and field of
+// an APIError as extension members.
+type problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code,omitempty"`
+	Field    string `json:"field,omitempty"`
+	Resource string `json:"resource,omitempty"`
+	ID       string `json:"id,omitempty"`
+}
+
+// newProblem describes err, which may be nil, as a problem for a request.
+func newProblem(r *http.Request, status int, err *APIError) problem {
+	p := problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Instance: r.URL.Path,
+	}
+	if err != nil {
+		if err.Type != "" {
+			p.Type = err.Type
+		}
+		p.Detail = err.Message
+		p.Code = err.Code
+		p.Field = err.Field
+		p.Resource = err.Resource
+		p.ID = err.ID
+	}
+	return p
+}
+
+// SetDescriptiveNotFound describes resources that are not found to the client,
+// writing ErrNotFound returned when getting, updating or deleting a resource
+// with a body naming the resource path and id:
+//
+//	{"error":{"status":404,"code":"not_found","resource":"users","id":"42"}}
+//
+// Not found errors are described by their status alone by default.
+func (s *Server) SetDescriptiveNotFound(enabled bool) {
+	s.descriptiveNotFound = enabled
+}
+
+// describeNotFound returns err, wrapped in an APIError naming the resource
+// identified by id when it is an undescribed ErrNotFound.
+func (s *Server) describeNotFound(reg *registration, id string, err error) error {
+	if !s.descriptiveNotFound || !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if _, ok := asAPIError(err); ok {
+		return err
+	}
+	return &APIError{
+		Status:   http.StatusNotFound,
+		Code:     "not_found",
+		Resource: reg.path,
+		ID:       id,
+		Err:      err,
+	}
+}
+
+// SetErrorBodies sets whether errors that have no description beyond their
+// status, such as ErrNotFound or unhandled errors, are written with a body
+// describing the status, so that clients always get an error object:
+//
+//	{"error":{"status":404,"code":"not_found","message":"Not found"}}
+//
+// It is on by default. Turning it off writes such errors without a body.
+// Problem details, see SetProblemJSON, always have a body.
+func (s *Server) SetErrorBodies(enabled bool) {
+	s.errorBodies = enabled
+}
+
+// statusError returns an APIError describing status by its status text.
+func statusError(status int) *APIError {
+	text := http.StatusText(status)
+	if text == "" {
+		return &APIError{Status: status}
+	}
+	code := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return unicode.ToLower(r)
+		case r == '\'':
+			return -1
+		}
+		return '_'
+	}, text)
+	return &APIError{
+		Status:  status,
+		Code:    code,
+		Message: text[:1] + strings.ToLower(text[1:]),
+	}
+}
+
+// asAPIError returns the APIError describing err to the client, or false when
+// err has no description beyond its status.
+func asAPIError(err error) (*APIError, bool) {
+	var apiErr *APIError
+	var validationErr *ValidationError
+	var httpErr HTTPError
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	} else if errors.As(err, &httpErr) && httpErr.StatusCode() != 0 {
+		apiErr = statusError(httpErr.StatusCode())
+		apiErr.Message = httpErr.Error()
+		apiErr.Err = err
+		return apiErr, true
+	} else if errors.As(err, &validationErr) {
+		return &APIError{
+			Status:  http.StatusUnprocessableEntity,
+			Code:    "invalid",
+			Message: validationErr.Message,
+			Field:   validationErr.Field,
+			Err:     err,
+		}, true
+	}
+	return nil, false
+}
+
+// errorStatus maps an error returned by a resource handler to a status code.
+func errorStatus(err error) int {
+	var apiErr *APIError
+	var validationErr *ValidationError
+	var httpErr HTTPError
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &apiErr) && apiErr.Status != 0 {
+		return apiErr.Status
+	} else if errors.As(err, &httpErr) && httpErr.StatusCode() != 0 {
+		return httpErr.StatusCode()
+	} else if errors.As(err, &validationErr) {
+		return http.StatusUnprocessableEntity
+	} else if errors.Is(err, ErrNotFound) {
+		return http.StatusNotFound
+	} else if errors.Is(err, ErrConflict) {
+		return http.StatusConflict
+	} else if errors.Is(err, ErrGone) {
+		return http.StatusGone
+	} else if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,301 @@
+package reason
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+type ConflictHandler struct{}
+
+func (ch ConflictHandler) Path() string {
+	return "conflict"
+}
+
+func (ch ConflictHandler) CreateResource(resource interface{}) (interface{}, error) {
+	switch resource.(TestResource).Name {
+	case "field":
+		return nil, NewConflict("name", "Name is taken")
+	case "wrapped":
+		return nil, fmt.Errorf("insert failed: %w", ErrConflict)
+	}
+	return nil, ErrConflict
+}
+
+func TestConflict(t *testing.T) {
+	var requests = []struct {
+		Name       string
+		StatusCode int
+		Body       string
+	}{
+		{"plain", 409, `{"error":{"status":409,"code":"conflict","message":"Conflict"}}`},
+		{"wrapped", 409, `{"error":{"status":409,"code":"conflict","message":"Conflict"}}`},
+		{"field", 409, `{"error":{"status":409,"code":"conflict","message":"Name is taken","field":"name"}}`},
+	}
+
+	s := New()
+	s.Add(TestResource{}, ConflictHandler{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		res, err := http.PostForm(ts.URL+"/conflict", url.Values{"name": {request.Name}})
+		if err != nil {
+			t.Errorf("%s: expected no error from PostForm, got %s", request.Name, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Name, request.StatusCode, res.StatusCode)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Name, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Name, request.Body, body)
+		}
+	}
+}
+
+type StatusHandler struct{}
+
+func (sh StatusHandler) Path() string {
+	return "status"
+}
+
+func (sh StatusHandler) GetResource(id string) (interface{}, error) {
+	switch id {
+	case "gone":
+		return nil, ErrGone
+	case "wrapped":
+		return nil, fmt.Errorf("soft deleted: %w", ErrGone)
+	case "legal":
+		return nil, &APIError{Status: http.StatusUnavailableForLegalReasons, Code: "unavailable", Message: "Unavailable in your region"}
+	case "status":
+		return nil, &APIError{Status: http.StatusForbidden}
+	case "unhandled":
+		return nil, errors.New("connection refused")
+	case "forbidden":
+		return nil, NewHTTPError(http.StatusForbidden, "Not your document")
+	case "locked":
+		return nil, fmt.Errorf("lookup: %w", lockedError{})
+	}
+	return nil, ErrNotFound
+}
+
+type lockedError struct{}
+
+func (le lockedError) Error() string {
+	return "document locked"
+}
+
+func (le lockedError) StatusCode() int {
+	return http.StatusLocked
+}
+
+func TestHTTPErrorStatusCode(t *testing.T) {
+	var _ HTTPError = &APIError{}
+
+	var httpErr HTTPError
+	if !errors.As(NewHTTPError(http.StatusForbidden, "Not your document"), &httpErr) {
+		t.Fatalf("expected NewHTTPError to return an HTTPError")
+	}
+	if status := httpErr.StatusCode(); status != http.StatusForbidden {
+		t.Errorf("expected status code 403, got %d", status)
+	}
+	if status := (&APIError{Err: ErrConflict}).StatusCode(); status != http.StatusConflict {
+		t.Errorf("expected status code 409 from the underlying error, got %d", status)
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	var requests = []struct {
+		Path       string
+		StatusCode int
+		Body       string
+	}{
+		{"/status/gone", 410, `{"error":{"status":410,"code":"gone","message":"Gone"}}`},
+		{"/status/wrapped", 410, `{"error":{"status":410,"code":"gone","message":"Gone"}}`},
+		{"/status/legal", 451, `{"error":{"status":451,"code":"unavailable","message":"Unavailable in your region"}}`},
+		{"/status/status", 403, `{"error":{"status":403}}`},
+		{"/status/forbidden", 403, `{"error":{"status":403,"message":"Not your document"}}`},
+		{"/status/locked", 423, `{"error":{"status":423,"code":"locked","message":"document locked"}}`},
+		{"/status/other", 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`},
+	}
+
+	s := New()
+	s.Add(TestResource{}, StatusHandler{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		res, err := http.Get(ts.URL + request.Path)
+		if err != nil {
+			t.Errorf("%s: expected no error from Get, got %s", request.Path, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Path, request.StatusCode, res.StatusCode)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Path, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Path, request.Body, body)
+		}
+	}
+}
+
+func TestErrorBodies(t *testing.T) {
+	var requests = []struct {
+		Path       string
+		StatusCode int
+		Body       string
+	}{
+		{"/status/gone", 410, `{"error":{"status":410,"code":"gone","message":"Gone"}}`},
+		{"/status/other", 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`},
+		{"/status/unhandled", 500, `{"error":{"status":500,"code":"internal_server_error","message":"Internal server error"}}`},
+		{"/status/legal", 451, `{"error":{"status":451,"code":"unavailable","message":"Unavailable in your region"}}`},
+		{"/unrouted", 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`},
+	}
+
+	s := New(WithLogger(log.New(ioutil.Discard, "", 0)))
+	s.Add(TestResource{}, StatusHandler{})
+
+	for _, request := range requests {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", request.Path, nil))
+
+		if w.Code != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Path, request.StatusCode, w.Code)
+		}
+		if body := w.Body.String(); body != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Path, request.Body, body)
+		}
+	}
+
+	// Turned off, only errors with a description have a body
+	s.SetErrorBodies(false)
+	for _, request := range requests {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", request.Path, nil))
+
+		body := request.Body
+		if request.Path != "/status/legal" {
+			body = ""
+		}
+		if w.Code != request.StatusCode {
+			t.Errorf("%s without error bodies: expected status code %d, got %d", request.Path, request.StatusCode, w.Code)
+		}
+		if w.Body.String() != body {
+			t.Errorf("%s without error bodies: expected body '%s', got '%s'", request.Path, body, w.Body.String())
+		}
+	}
+}
+
+func TestProblemJSON(t *testing.T) {
+	var requests = []struct {
+		Name       string
+		StatusCode int
+		Body       string
+	}{
+		{"plain", 409, `{"type":"about:blank","title":"Conflict","status":409,"instance":"/conflict"}`},
+		{"field", 409, `{"type":"about:blank","title":"Conflict","status":409,"detail":"Name is taken","instance":"/conflict","code":"conflict","field":"name"}`},
+	}
+
+	s := New()
+	s.SetProblemJSON(true)
+	s.Add(TestResource{}, ConflictHandler{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		res, err := http.PostForm(ts.URL+"/conflict", url.Values{"name": {request.Name}})
+		if err != nil {
+			t.Fatalf("%s: expected no error from PostForm, got %s", request.Name, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Name, request.StatusCode, res.StatusCode)
+		}
+		if contentType := res.Header.Get("Content-Type"); contentType != "application/problem+json; charset=utf-8" {
+			t.Errorf("%s: expected Content-Type 'application/problem+json; charset=utf-8', got '%s'", request.Name, contentType)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Name, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Name, request.Body, body)
+		}
+	}
+
+	res, err := http.Get(ts.URL + "/missing")
+	if err != nil {
+		t.Fatalf("expected no error from Get, got %s", err.Error())
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != `{"type":"about:blank","title":"Not Found","status":404,"instance":"/missing"}` {
+		t.Errorf("expected not found problem, got '%s'", body)
+	}
+}
+
+func TestDescriptiveNotFound(t *testing.T) {
+	var requests = []struct {
+		Method string
+		Path   string
+		Body   string
+	}{
+		{"GET", "/test/42", `{"error":{"status":404,"code":"not_found","resource":"test","id":"42"}}`},
+		{"DELETE", "/v2/test/7", `{"error":{"status":404,"code":"not_found","resource":"v2/test","id":"7"}}`},
+		{"GET", "/missing/1", `{"error":{"status":404,"code":"not_found","message":"Not found"}}`},
+	}
+
+	s := New()
+	s.SetDescriptiveNotFound(true)
+	s.Add(TestResource{}, TestResourceHandler{})
+	s.Add(TestResource{}, TestResourceHandler{}, WithVersion("v2"))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		req, err := http.NewRequest(request.Method, ts.URL+request.Path, nil)
+		if err != nil {
+			t.Fatalf("%s: expected no error from http.NewRequest, got %s", request.Path, err.Error())
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: expected no error from request, got %s", request.Path, err.Error())
+		}
+
+		if res.StatusCode != 404 {
+			t.Errorf("%s: expected status code 404, got %d", request.Path, res.StatusCode)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Path, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Path, request.Body, body)
+		}
+	}
+}
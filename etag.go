@@ -0,0 +1,136 @@
+package reason
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// resourceETag computes a strong entity tag for a resource from its JSON
+// representation.
+func resourceETag(res interface{}) (string, error) {
+	out, err := json.Marshal(res)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha1.Sum(out)
+	return `"` + hex.EncodeToString(sum[:]) + `"`, nil
+}
+
+// weakETag returns the weak form of a strong entity tag. Lists are tagged
+// weakly, as handlers may order items differently without the list changing
+// meaningfully, while resources are tagged strongly by their representation.
+func weakETag(etag string) string {
+	return "W/" + etag
+}
+
+// ListVersioner implementers can return a version token that changes
+// whenever the list of a resource does, such as a counter or the time of the
+// last change. The ETag of a list is then derived from the token and the query
+// of the request, and a list matching If-None-Match is answered with
+// http.StatusNotModified without being listed. Lists of other handlers are
+// tagged by their JSON representation.
+type ListVersioner interface {
+	Lister
+	ListVersion() (string, error)
+}
+
+// listETag computes a weak entity tag for a list of version, varying with the
+// query and negotiated format of the request.
+func listETag(r *http.Request, version string) string {
+	sum := sha1.Sum([]byte(version + "\n" + r.URL.RawQuery + "\n" + formatFromContext(r.Context())))
+	return weakETag(`"` + hex.EncodeToString(sum[:]) + `"`)
+}
+
+// etagNoneMatch reports whether etag satisfies an If-None-Match header value,
+// which may be "*" or a comma separated list of entity tags. Tags are compared
+// weakly, ignoring the W/ prefix of either, as RFC 7232 requires for
+// If-None-Match.
+func etagNoneMatch(header string, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkNotModified sets the ETag header of a response to etag and writes
+// http.StatusNotModified, returning true, when it satisfies the If-None-Match
+// header of the request. RFC 7232 requires a 304 to carry the headers a 200
+// would, such as Cache-Control and Vary, so they must be set before.
+func checkNotModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	match := r.Header.Get("If-None-Match")
+	if match == "" || !etagNoneMatch(match, etag) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatch reports whether etag satisfies an If-Match header value. The
+// header may be "*" or a comma separated list of entity tags. Weak tags never
+// match, as If-Match requires a strong comparison.
+func etagMatch(header string, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// SetRequirePrecondition rejects updates and deletes sent without an If-Match
+// header with http.StatusPreconditionRequired, so that clients cannot
+// overwrite changes they have not seen. It is off by default.
+func (s *Server) SetRequirePrecondition(require bool) {
+	s.requirePrecondition = require
+}
+
+// checkPrecondition returns an error if preconditions are required and the
+// request has no If-Match header.
+func (s *Server) checkPrecondition(r *http.Request) error {
+	if !s.requirePrecondition || r.Header.Get("If-Match") != "" {
+		return nil
+	}
+	return &APIError{
+		Status:  http.StatusPreconditionRequired,
+		Code:    "precondition_required",
+		Message: "If-Match header is required",
+	}
+}
+
+// checkIfMatch evaluates the If-Match header of an unsafe request against the
+// current representation of res. It writes http.StatusPreconditionFailed and
+// returns false when the header does not match.
+func (s *Server) checkIfMatch(w http.ResponseWriter, r *http.Request, reg *registration, handler interface{}, res interface{}) bool {
+	match := r.Header.Get("If-Match")
+	if match == "" {
+		return true
+	}
+
+	rep, err := s.represent(reg, handler, res)
+	if err == nil {
+		rep, err = authorizeFields(r, reg, handler, res, rep)
+	}
+	if err != nil {
+		s.writeError(w, r, err)
+		return false
+	}
+	etag, err := resourceETag(rep)
+	if err != nil {
+		s.writeError(w, r, err)
+		return false
+	}
+	if !etagMatch(match, etag) {
+		s.writeError(w, r, statusError(http.StatusPreconditionFailed))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package reason
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Expander implementers can embed related resources in GET responses when the
+// client asks for them with the expand query parameter, for example
+// ?expand=author,comments. Relations lists the relation names that may be
+// requested; any other name is rejected with http.StatusBadRequest.
+type Expander interface {
+	Relations() []string
+	Expand(resource interface{}, relations []string) (interface{}, error)
+}
+
+// expandRelations returns the relations requested by the expand query
+// parameter, validated against those supported by the expander.
+func (s *Server) expandRelations(r *http.Request, expander Expander) ([]string, error) {
+	param := r.URL.Query().Get(s.param("expand"))
+	if param == "" {
+		return nil, nil
+	}
+
+	supported := expander.Relations()
+	var relations []string
+	for _, name := range strings.Split(param, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		found := false
+		for _, relation := range supported {
+			if relation == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, &APIError{
+				Status:  http.StatusBadRequest,
+				Code:    "invalid_expand",
+				Message: fmt.Sprintf("Unknown relation %q", name),
+				Field:   s.param("expand"),
+			}
+		}
+		relations = append(relations, name)
+	}
+	return relations, nil
+}
@@ -0,0 +1,84 @@
+package reason
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type ExpandedTestResource struct {
+	TestResource
+	Author string `json:"author,omitempty"`
+	Tags   string `json:"tags,omitempty"`
+}
+
+type ExpandHandler struct {
+	TestResourceHandler
+}
+
+func (eh ExpandHandler) Path() string {
+	return "expand"
+}
+
+func (eh ExpandHandler) Relations() []string {
+	return []string{"author", "tags"}
+}
+
+func (eh ExpandHandler) Expand(resource interface{}, relations []string) (interface{}, error) {
+	expanded := ExpandedTestResource{TestResource: resource.(TestResource)}
+	for _, relation := range relations {
+		switch relation {
+		case "author":
+			expanded.Author = "Author of " + expanded.Name
+		case "tags":
+			expanded.Tags = strings.ToLower(expanded.Name)
+		}
+	}
+	return expanded, nil
+}
+
+func TestExpander(t *testing.T) {
+	var requests = []struct {
+		Path       string
+		StatusCode int
+		Body       string
+	}{
+		{"/expand/1", 200, `{"id":1,"name":"The Test"}`},
+		{"/expand/1?expand=author", 200, `{"id":1,"name":"The Test","author":"Author of The Test"}`},
+		{"/expand/1?expand=author,%20tags", 200, `{"id":1,"name":"The Test","author":"Author of The Test","tags":"the test"}`},
+		{"/expand/1?expand=editor", 400, `{"error":{"status":400,"code":"invalid_expand","message":"Unknown relation \"editor\"","field":"expand"}}`},
+		{"/expand/3?expand=author", 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`},
+		{"/expand?expand=tags", 200, `[{"id":1,"name":"The Test","tags":"the test"},{"id":2,"name":"The Other","tags":"the other"}]`},
+		{"/expand?expand=editor", 400, `{"error":{"status":400,"code":"invalid_expand","message":"Unknown relation \"editor\"","field":"expand"}}`},
+		{"/test/1?expand=author", 200, `{"id":1,"name":"The Test"}`},
+	}
+
+	s := New()
+	s.Add(TestResource{}, ExpandHandler{})
+	s.Add(TestResource{}, TestResourceHandler{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		res, err := http.Get(ts.URL + request.Path)
+		if err != nil {
+			t.Errorf("%s: expected no error from Get, got %s", request.Path, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Path, request.StatusCode, res.StatusCode)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Path, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Path, request.Body, body)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package reason
+
+import (
+	"context"
+	"net/http"
+)
+
+// FieldAuthorizer implementers restrict the fields of resources written to
+// each client, such as to show a salary only to clients with a manager role
+// in their claims, see ClaimsFromContext. AuthorizedFields returns the JSON
+// names of the fields of resource the client of ctx may see, or nil to allow
+// every field. Other fields, including computed fields, are left out of
+// gets, lists and the responses of creates and updates.
+type FieldAuthorizer interface {
+	AuthorizedFields(ctx context.Context, resource interface{}) []string
+}
+
+// authorizeFields removes the fields of rep, the representation of res, that
+// the client is not authorized to see. Values other than resources of the
+// schema are written as they are.
+func authorizeFields(r *http.Request, reg *registration, handler interface{}, res interface{}, rep interface{}) (interface{}, error) {
+	fa, ok := handler.(FieldAuthorizer)
+	if !ok || !reg.isSchema(res) {
+		return rep, nil
+	}
+	allowed := fa.AuthorizedFields(r.Context(), res)
+	if allowed == nil {
+		return rep, nil
+	}
+
+	obj, err := newJSONObject(rep)
+	if err != nil {
+		return nil, err
+	}
+	keep := make(map[string]bool, len(allowed))
+	for _, name := range allowed {
+		keep[name] = true
+	}
+	for _, key := range append([]string(nil), obj.keys...) {
+		if !keep[key] {
+			obj.Delete(key)
+		}
+	}
+	return obj, nil
+}
@@ -0,0 +1,67 @@
+package reason
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type RestrictedHandler struct {
+	TestResourceHandler
+}
+
+func (rh RestrictedHandler) Path() string {
+	return "restricted"
+}
+
+func (rh RestrictedHandler) AuthorizedFields(ctx context.Context, resource interface{}) []string {
+	if ClaimsFromContext(ctx)["role"] == "admin" {
+		return nil
+	}
+	if resource.(TestResource).ID == 2 {
+		return []string{}
+	}
+	return []string{"id"}
+}
+
+func TestFieldAuthorizer(t *testing.T) {
+	var requests = []struct {
+		Method string
+		Path   string
+		Role   string
+		Body   string
+	}{
+		{"GET", "/restricted/1", "admin", `{"id":1,"name":"The Test"}`},
+		{"GET", "/restricted/1", "user", `{"id":1}`},
+		{"GET", "/restricted", "user", `[{"id":1},{}]`},
+		{"POST", "/restricted", "user", `{"id":3}`},
+		{"POST", "/restricted/1", "user", `{"id":1}`},
+		{"POST", "/restricted/1", "admin", `{"id":1,"name":"New"}`},
+	}
+
+	s := New()
+	s.SetJWTVerifier(func(token string) (Claims, error) {
+		return Claims{"role": token}, nil
+	})
+	s.Add(TestResource{}, RestrictedHandler{})
+
+	for _, request := range requests {
+		var body string
+		if request.Method == "POST" {
+			body = url.Values{"name": {"New"}}.Encode()
+		}
+		req := httptest.NewRequest(request.Method, request.Path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+request.Role)
+		if body != "" {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		if body := rec.Body.String(); body != request.Body {
+			t.Errorf("%s %s (%s): expected body '%s', got '%s'", request.Method, request.Path, request.Role, request.Body, body)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package reason
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// WithRequired requires the named schema fields, such as "email", to be set
+// by create requests. Creates that leave a required field at its zero value,
+// after defaults are applied, respond with http.StatusUnprocessableEntity.
+// Rules are kept per resource, so a schema type added several times, such as
+// once per tenant, can require different fields in each.
+func WithRequired(fields ...string) ResourceOption {
+	return func(reg *registration) {
+		if reg.required == nil {
+			reg.required = make(map[string]bool)
+		}
+		for _, field := range fields {
+			reg.required[field] = true
+		}
+	}
+}
+
+// WithDefault sets the value a create request gives the named schema field
+// when it leaves the field at its zero value. value is parsed as a form value
+// would be, so WithDefault("role", "member") and WithDefault("limit", "10")
+// suit string and int fields. Add panics if the value cannot be parsed into
+// the field.
+func WithDefault(field, value string) ResourceOption {
+	return func(reg *registration) {
+		if reg.defaults == nil {
+			reg.defaults = make(map[string]string)
+		}
+		reg.defaults[field] = value
+	}
+}
+
+// checkFieldRules returns an error if the field rules of reg name a field
+// its schema does not have, or a default that cannot be parsed.
+func (s *Server) checkFieldRules(reg *registration) error {
+	if len(reg.required) == 0 && len(reg.defaults) == 0 {
+		return nil
+	}
+	t := reflect.TypeOf(reg.schema)
+	if t == nil || t.Kind() != reflect.Struct {
+		return fmt.Errorf("reason: field rules need a struct schema, got %s", t)
+	}
+
+	fields, err := s.getSchemaFields(schemaKey{t, reg, s.tagName})
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(fields))
+	val := reflect.New(t).Elem()
+	for _, field := range fields {
+		known[field.name] = true
+		if field.hasDef {
+			if err := setFormValue(settableField(val, field.index), field.def); err != nil {
+				return fmt.Errorf("reason: default of field %q of %s: %w", field.name, t, err)
+			}
+		}
+	}
+	for name := range reg.required {
+		if !known[name] {
+			return fmt.Errorf("reason: required field %q is not a field of %s", name, t)
+		}
+	}
+	for name := range reg.defaults {
+		if !known[name] {
+			return fmt.Errorf("reason: default field %q is not a field of %s", name, t)
+		}
+	}
+	return nil
+}
+
+// applyFieldRules returns a copy of data, a parsed resource of the schema of
+// reg, with the defaults of its zero valued fields set, or a ValidationError
+// naming the first required field left at its zero value.
+func (s *Server) applyFieldRules(reg *registration, data interface{}) (interface{}, error) {
+	t := reflect.TypeOf(reg.schema)
+	if (len(reg.required) == 0 && len(reg.defaults) == 0) || reflect.TypeOf(data) != t {
+		return data, nil
+	}
+
+	fields, err := s.getSchemaFields(schemaKey{t, reg, s.tagName})
+	if err != nil {
+		return nil, err
+	}
+
+	val := reflect.New(t).Elem()
+	val.Set(reflect.ValueOf(data))
+	for _, field := range fields {
+		v, ok := fieldValue(val, field.index)
+		if field.hasDef && (!ok || v.IsZero()) {
+			v, ok = settableField(val, field.index), true
+			if err := setFormValue(v, field.def); err != nil {
+				return nil, err
+			}
+		}
+		if field.required && (!ok || v.IsZero()) {
+			return nil, &ValidationError{Field: field.name, Message: "Field is required"}
+		}
+	}
+	return val.Interface(), nil
+}
@@ -0,0 +1,84 @@
+package reason
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+type RuledResource struct {
+	ID    int64  `json:"id"`
+	Email string `json:"email"`
+	Role  string `json:"role"`
+	Limit int    `json:"limit"`
+}
+
+func TestFieldRules(t *testing.T) {
+	var requests = []struct {
+		Path   string
+		Data   url.Values
+		Status int
+		Body   string
+	}{
+		{"/a/ruled", url.Values{"role": {"admin"}}, 422, `{"error":{"status":422,"code":"invalid","message":"Field is required","field":"email"}}`},
+		{"/a/ruled", url.Values{"email": {"jo@example.com"}}, 201, `{"id":1,"email":"jo@example.com","role":"member","limit":10}`},
+		{"/a/ruled", url.Values{"email": {"al@example.com"}, "role": {"admin"}}, 201, `{"id":2,"email":"al@example.com","role":"admin","limit":10}`},
+		{"/b/ruled", url.Values{"role": {"admin"}}, 201, `{"id":1,"email":"","role":"admin","limit":0}`},
+	}
+
+	s := New()
+	s.Add(RuledResource{}, NewMapStore("ruled"), WithVersion("a"), WithRequired("email"), WithDefault("role", "member"), WithDefault("limit", "10"))
+	s.Add(RuledResource{}, NewMapStore("ruled"), WithVersion("b"))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		res, err := http.PostForm(ts.URL+request.Path, request.Data)
+		if err != nil {
+			t.Fatalf("%s: expected no error from PostForm, got %s", request.Path, err.Error())
+		}
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Path, err.Error())
+		}
+
+		if res.StatusCode != request.Status {
+			t.Errorf("%s: expected status code %d, got %d", request.Path, request.Status, res.StatusCode)
+		}
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Path, request.Body, body)
+		}
+	}
+
+	s.Remove("a/ruled")
+	for key := range s.formCache {
+		if key.reg != nil && key.reg.path == "a/ruled" {
+			t.Errorf("expected the fields of a removed resource to be forgotten")
+		}
+	}
+}
+
+func TestFieldRulesInvalid(t *testing.T) {
+	var options = []struct {
+		Name   string
+		Option ResourceOption
+	}{
+		{"unknown required", WithRequired("missing")},
+		{"unknown default", WithDefault("missing", "x")},
+		{"bad default", WithDefault("limit", "ten")},
+	}
+
+	for _, option := range options {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected Add to panic", option.Name)
+				}
+			}()
+			New().Add(RuledResource{}, NewMapStore("ruled"), option.Option)
+		}()
+	}
+}
@@ -0,0 +1,54 @@
+package reason
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Flags is the set of feature flags enabled for a request.
+type Flags map[string]bool
+
+// Has reports whether flag is enabled.
+func (f Flags) Has(flag string) bool {
+	return f[flag]
+}
+
+// SetFlagsHeader reads the feature flags of each request from the header
+// name, such as "X-Feature-Flags: newserializer,betaexpand", and stores them
+// in the request context, see FlagsFromContext. The header is meant to be set
+// by a trusted edge layer.
+func (s *Server) SetFlagsHeader(name string) {
+	s.flagsHeader = name
+}
+
+// FlagsFromContext returns the feature flags of a request, or nil when the
+// request has none or the server has no flags header configured.
+func FlagsFromContext(ctx context.Context) Flags {
+	flags, _ := ctx.Value(flagsKey).(Flags)
+	return flags
+}
+
+// withFlags stores the feature flags of a request in its context.
+func (s *Server) withFlags(r *http.Request) *http.Request {
+	if s.flagsHeader == "" {
+		return r
+	}
+
+	var flags Flags
+	for _, value := range r.Header.Values(s.flagsHeader) {
+		for _, flag := range strings.Split(value, ",") {
+			if flag = strings.TrimSpace(flag); flag == "" {
+				continue
+			}
+			if flags == nil {
+				flags = make(Flags)
+			}
+			flags[flag] = true
+		}
+	}
+	if flags == nil {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), flagsKey, flags))
+}
@@ -0,0 +1,73 @@
+package reason
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type FlagsHandler struct {
+	ctx context.Context
+}
+
+func (fh FlagsHandler) Path() string {
+	return "flags"
+}
+
+func (fh FlagsHandler) WithContext(ctx context.Context) ResourceHandler {
+	fh.ctx = ctx
+	return fh
+}
+
+func (fh FlagsHandler) GetResource(id string) (interface{}, error) {
+	flags := FlagsFromContext(fh.ctx)
+	name := "old"
+	if flags.Has("new") {
+		name = "new"
+	}
+	return TestResource{int64(len(flags)), name}, nil
+}
+
+func TestFlags(t *testing.T) {
+	var requests = []struct {
+		Flags []string
+		Body  string
+	}{
+		{nil, `{"id":0,"name":"old"}`},
+		{[]string{" , "}, `{"id":0,"name":"old"}`},
+		{[]string{"beta, new"}, `{"id":2,"name":"new"}`},
+		{[]string{"beta", "new,other"}, `{"id":3,"name":"new"}`},
+	}
+
+	s := New()
+	s.SetFlagsHeader("X-Feature-Flags")
+	s.Add(TestResource{}, FlagsHandler{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		req, err := http.NewRequest("GET", ts.URL+"/flags/1", nil)
+		if err != nil {
+			t.Fatalf("%v: expected no error from http.NewRequest, got %s", request.Flags, err.Error())
+		}
+		for _, flags := range request.Flags {
+			req.Header.Add("X-Feature-Flags", flags)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%v: expected no error from request, got %s", request.Flags, err.Error())
+		}
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%v: expected no error from read, got %s", request.Flags, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%v: expected body '%s', got '%s'", request.Flags, request.Body, body)
+		}
+	}
+}
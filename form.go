@@ -1,94 +1,642 @@
 package reason
 
 import (
+	"encoding"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 )
 
+// defaultMaxMemory is the number of bytes of a multipart body kept in memory,
+// matching the default used by net/http.
+const defaultMaxMemory = 32 << 20
+
+// schemaKey identifies the cached fields of a schema. Fields are cached per
+// type, per registration and per struct tag naming the fields, where an empty
+// tag is the json tag. Fields cached for a registration carry the rules it was
+// added with, such as WithRequired, so registrations sharing a type do not
+// share them; a nil registration caches the fields as declared by the type.
+type schemaKey struct {
+	typ reflect.Type
+	reg *registration
+	tag string
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
 type formField struct {
-	name  string
-	typ   reflect.Type
-	index []int
+	name       string
+	typ        reflect.Type
+	index      []int
+	id         bool
+	sensitive  bool
+	filterable bool
+	sortable   bool
+	immutable  bool
+	optional   bool
+	createdAt  bool
+	updatedAt  bool
+	createdBy  bool
+	updatedBy  bool
+	required   bool
+	def        string
+	hasDef     bool
+
+	// tagged and quoted record the name and ",string" option of the
+	// field's tag, which decide its encoding as they do for encoding/json.
+	tagged bool
+	quoted bool
 }
 
-func (s *Server) getSchemaFields(t reflect.Type) ([]formField, error) {
+func (s *Server) getSchemaFields(key schemaKey) ([]formField, error) {
 	s.formCacheLock.RLock()
-	fields := s.formCache[t]
+	fields := s.formCache[key]
 	s.formCacheLock.RUnlock()
 	if fields != nil {
 		return fields, nil
 	}
 
-	fields = make([]formField, 0, t.NumField())
+	if key.reg != nil {
+		return s.getRegistrationFields(key)
+	}
+
+	t := key.typ
+	tagName := key.tag
+	if tagName == "" {
+		tagName = "json"
+	}
+	fields = dominantFields(appendSchemaFields(make([]formField, 0, t.NumField()), t, nil, tagName))
+	if idIndex, ok := idFieldIndex(t); ok {
+		for k := range fields {
+			fields[k].id = len(fields[k].index) == 1 && fields[k].index[0] == idIndex[0]
+		}
+	}
+
+	s.formCacheLock.Lock()
+	s.formCache[key] = fields
+	s.formCacheLock.Unlock()
+
+	return fields, nil
+}
+
+// getRegistrationFields returns the fields of the schema key.typ with the
+// field rules of key.reg applied. The fields of the type are copied, so that
+// the rules of one registration are not seen by another.
+func (s *Server) getRegistrationFields(key schemaKey) ([]formField, error) {
+	typeFields, err := s.getSchemaFields(schemaKey{typ: key.typ, tag: key.tag})
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]formField, len(typeFields))
+	copy(fields, typeFields)
+	for k := range fields {
+		fields[k].required = key.reg.required[fields[k].name]
+		fields[k].def, fields[k].hasDef = key.reg.defaults[fields[k].name]
+	}
+
+	s.formCacheLock.Lock()
+	s.formCache[key] = fields
+	s.formCacheLock.Unlock()
+
+	return fields, nil
+}
+
+// forgetSchemaFields drops the cached fields of the registrations removed
+// from path.
+func (s *Server) forgetSchemaFields(path string) {
+	s.formCacheLock.Lock()
+	defer s.formCacheLock.Unlock()
+	for key := range s.formCache {
+		if key.reg != nil && key.reg.path == path {
+			delete(s.formCache, key)
+		}
+	}
+}
+
+// appendSchemaFields appends the fields of the struct type t, named by the
+// tagName struct tag and flattening embedded structs and struct pointers as
+// encoding/json does. Unexported fields, fields tagged "-" and embedded
+// pointers to unexported structs are skipped.
+func appendSchemaFields(fields []formField, t reflect.Type, parent []int, tagName string) []formField {
 	for i := 0; i < t.NumField(); i++ {
 		sfield := t.Field(i)
+		tag := sfield.Tag.Get(tagName)
+		if tag == "-" {
+			continue
+		}
+		var opts string
+		if idx := strings.Index(tag, ","); idx != -1 {
+			tag, opts = tag[0:idx], tag[idx:]
+		}
+
+		index := make([]int, len(parent), len(parent)+1)
+		copy(index, parent)
+		index = append(index, i)
+
+		if sfield.Anonymous && tag == "" {
+			ft := sfield.Type
+			if ft.Kind() == reflect.Ptr {
+				if sfield.PkgPath != "" {
+					continue
+				}
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = appendSchemaFields(fields, ft, index, tagName)
+				continue
+			}
+		}
+		if sfield.PkgPath != "" {
+			continue
+		}
+
 		field := formField{}
-		tag := sfield.Tag.Get("json")
 		if tag != "" {
-			if idx := strings.Index(tag, ","); idx != -1 {
-				field.name = tag[0:idx]
-			} else {
-				field.name = tag
-			}
+			field.name = tag
+			field.tagged = true
 		} else {
 			field.name = sfield.Name
 		}
 		field.typ = sfield.Type
-		field.index = sfield.Index
+		field.index = index
+		field.quoted = strings.Contains(opts+",", ",string,") && quotable(field.typ)
+		for _, opt := range strings.Split(sfield.Tag.Get("reason"), ",") {
+			switch opt {
+			case "sensitive":
+				field.sensitive = true
+			case "filterable":
+				field.filterable = true
+			case "sortable":
+				field.sortable = true
+			case "immutable":
+				field.immutable = true
+			case "optional":
+				field.optional = true
+			case "createdAt":
+				field.createdAt = field.typ == timeType
+			case "updatedAt":
+				field.updatedAt = field.typ == timeType
+			case "createdBy":
+				field.createdBy = field.typ.Kind() == reflect.String
+			case "updatedBy":
+				field.updatedBy = field.typ.Kind() == reflect.String
+			}
+		}
 		fields = append(fields, field)
 	}
+	return fields
+}
+
+// quotable reports whether the ",string" tag option applies to a field of
+// type t, as it does in encoding/json.
+func quotable(t reflect.Type) bool {
+	if t.Name() == "" && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
 
-	s.formCacheLock.Lock()
-	s.formCache[t] = fields
-	s.formCacheLock.Unlock()
+// dominantFields drops fields hidden by a field with the same name, keeping
+// the least nested one as encoding/json does. Of fields nested equally deep,
+// only a single tagged one is kept; otherwise the name is ambiguous and
+// all of them are dropped.
+func dominantFields(fields []formField) []formField {
+	depth := make(map[string]int)
+	for _, field := range fields {
+		if d, ok := depth[field.name]; !ok || len(field.index) < d {
+			depth[field.name] = len(field.index)
+		}
+	}
 
-	return fields, nil
+	// Of the least nested fields with a name, a tagged one hides the others
+	candidates := make(map[string][]int)
+	for k, field := range fields {
+		if len(field.index) == depth[field.name] {
+			candidates[field.name] = append(candidates[field.name], k)
+		}
+	}
+	dominant := make(map[int]bool, len(candidates))
+	for _, ks := range candidates {
+		if len(ks) == 1 {
+			dominant[ks[0]] = true
+			continue
+		}
+		var tagged []int
+		for _, k := range ks {
+			if fields[k].tagged {
+				tagged = append(tagged, k)
+			}
+		}
+		if len(tagged) == 1 {
+			dominant[tagged[0]] = true
+		}
+	}
+
+	out := make([]formField, 0, len(dominant))
+	for k, field := range fields {
+		if dominant[k] {
+			out = append(out, field)
+		}
+	}
+	return out
 }
 
-func (s *Server) parseForm(r *http.Request, schema interface{}) (interface{}, error) {
-	t := reflect.TypeOf(schema)
-	fields, err := s.getSchemaFields(t)
+// fieldValue returns the field of the struct value v at index, or false when
+// the field is in an embedded struct pointer that is nil.
+func fieldValue(v reflect.Value, index []int) (reflect.Value, bool) {
+	f, err := v.FieldByIndexErr(index)
+	return f, err == nil
+}
+
+// settableField returns the field of the struct value v at index, allocating
+// the embedded struct pointers it is in when they are nil.
+func settableField(v reflect.Value, index []int) reflect.Value {
+	for k, i := range index {
+		if k > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v
+}
+
+// readForm parses the request body into r.Form. Malformed bodies are left for
+// the resource handler to validate, but a body over the configured limit is
+// reported.
+func readForm(r *http.Request) error {
+	var maxBytesErr *http.MaxBytesError
+	if err := r.ParseForm(); errors.As(err, &maxBytesErr) {
+		return err
+	}
+	if err := r.ParseMultipartForm(defaultMaxMemory); errors.As(err, &maxBytesErr) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) parseForm(r *http.Request, reg *registration) (interface{}, error) {
+	t := reflect.TypeOf(reg.schema)
+	if t.Kind() == reflect.Map {
+		if err := readForm(r); err != nil {
+			return nil, err
+		}
+		return s.parseFormMap(r.Form, t)
+	}
+
+	fields, err := s.getSchemaFields(schemaKey{typ: t, tag: s.tagName})
 	if err != nil {
 		return nil, err
 	}
 
+	if err := readForm(r); err != nil {
+		return nil, err
+	}
+
+	if s.disallowUnknownFields {
+		if err := s.checkFormKeys(r.Form, fields); err != nil {
+			return nil, err
+		}
+	}
+
 	// Create a new instance to write to
 	val := reflect.New(t).Elem()
 
+	update := OperationFromContext(r.Context()) == OperationUpdate
 	for _, field := range fields {
-		formval := r.FormValue(field.name)
+		if update && s.isImmutable(reg, field) {
+			continue
+		}
+		if isStructSlice(field.typ) {
+			if err := s.bindIndexedFields(val, t, field, r.Form); err != nil {
+				return nil, err
+			}
+			continue
+		}
 
 		// Ignore empty values and let the resource handler validate
-		if formval != "" {
-			switch field.typ.Kind() {
-			case reflect.String:
-				val.FieldByIndex(field.index).SetString(formval)
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				intval, err := strconv.ParseInt(formval, 10, 64)
-				if err != nil {
-					return nil, err
-				}
-				val.FieldByIndex(field.index).SetInt(intval)
-			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-				uintval, err := strconv.ParseUint(formval, 10, 64)
-				if err != nil {
-					return nil, err
-				}
-				val.FieldByIndex(field.index).SetUint(uintval)
-			case reflect.Float32, reflect.Float64:
-				floatval, err := strconv.ParseFloat(formval, 64)
-				if err != nil {
-					return nil, err
-				}
-				val.FieldByIndex(field.index).SetFloat(floatval)
-			case reflect.Bool:
-				boolval := formval == "true" || formval == "1"
-				val.FieldByIndex(field.index).SetBool(boolval)
+		if formval := r.FormValue(field.name); formval != "" {
+			if err := setFormValue(settableField(val, field.index), formval); err != nil {
+				return nil, bindError(t, field.name, field.typ, formval, err)
 			}
 		}
 	}
 
 	return val.Interface(), nil
 }
+
+// parseFormMap binds form to a new map of type t, which must have string keys.
+// Every key other than the reserved parameters is set, to the value parsed
+// into the element type of the map. Maps of interface{} hold the value as a
+// string, or as a []string for keys with several values.
+func (s *Server) parseFormMap(form url.Values, t reflect.Type) (interface{}, error) {
+	if t.Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("reason: map schema %s must have string keys", t)
+	}
+
+	m := reflect.MakeMapWithSize(t, len(form))
+	for key, vals := range form {
+		if len(vals) == 0 || s.isReservedParam(key) {
+			continue
+		}
+
+		elem := reflect.New(t.Elem()).Elem()
+		if elem.Kind() == reflect.Interface {
+			if len(vals) > 1 {
+				elem.Set(reflect.ValueOf(vals))
+			} else {
+				elem.Set(reflect.ValueOf(vals[0]))
+			}
+		} else if err := setFormValue(elem, vals[0]); err != nil {
+			return nil, bindError(t, key, t.Elem(), vals[0], err)
+		}
+		m.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), elem)
+	}
+	return m.Interface(), nil
+}
+
+// isImmutable reports whether updates keep the value of field, a field of the
+// schema of reg. Fields tagged createdAt are immutable when timestamps are
+// stamped, and fields tagged createdBy when the resource is audited.
+func (s *Server) isImmutable(reg *registration, field formField) bool {
+	return field.immutable || (field.createdAt && s.timestamps) || (field.createdBy && reg.audit)
+}
+
+// copyImmutableFields sets the immutable fields of the struct value dst, of
+// the schema of reg, to their values in src, or to their zero values when src
+// is not valid.
+func (s *Server) copyImmutableFields(reg *registration, dst reflect.Value, src reflect.Value) error {
+	fields, err := s.getSchemaFields(schemaKey{typ: dst.Type(), tag: s.tagName})
+	if err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		if !s.isImmutable(reg, field) {
+			continue
+		}
+		if src.IsValid() {
+			if value, ok := fieldValue(src, field.index); ok {
+				settableField(dst, field.index).Set(value)
+				continue
+			}
+		}
+		if value, ok := fieldValue(dst, field.index); ok {
+			value.Set(reflect.Zero(field.typ))
+		}
+	}
+	return nil
+}
+
+// bindError returns the error for a form value that cannot be bound to the
+// field name of the schema t, naming both so that errors can be traced to a
+// resource, for example:
+//
+//	User: field "age": invalid int "abc"
+//
+// Unnamed schemas, such as map[string]int, are named by their type.
+func bindError(t reflect.Type, name string, typ reflect.Type, value string, err error) error {
+	schema := t.Name()
+	if schema == "" {
+		schema = t.String()
+	}
+	return &APIError{
+		Status:  http.StatusBadRequest,
+		Code:    "invalid_body",
+		Message: fmt.Sprintf("%s: field %q: invalid %s %q", schema, name, typ, value),
+		Field:   name,
+		Err:     err,
+	}
+}
+
+// setFormValue parses formval into v, which must be a kind formBindable
+// accepts. Values of other kinds are ignored.
+func setFormValue(v reflect.Value, formval string) error {
+	if reflect.PtrTo(v.Type()).Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(formval))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(formval)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		intval, err := strconv.ParseInt(formval, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(intval)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		uintval, err := strconv.ParseUint(formval, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetUint(uintval)
+	case reflect.Float32, reflect.Float64:
+		floatval, err := strconv.ParseFloat(formval, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(floatval)
+	case reflect.Bool:
+		v.SetBool(formval == "true" || formval == "1")
+	}
+	return nil
+}
+
+// isStructSlice reports whether t is a slice of structs, which is bound from
+// indexed form keys.
+func isStructSlice(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Struct &&
+		!reflect.PtrTo(t.Elem()).Implements(textUnmarshalerType)
+}
+
+// bindIndexedFields binds form keys such as "items[0].name" to the elements
+// of field, a slice of structs in val, a value of the schema t. Elements are
+// ordered by index, gaps between indexes are closed.
+func (s *Server) bindIndexedFields(val reflect.Value, t reflect.Type, field formField, form url.Values) error {
+	prefix := field.name + "["
+	values := make(map[int]map[string]string)
+	for key, vals := range form {
+		if !strings.HasPrefix(key, prefix) || len(vals) == 0 {
+			continue
+		}
+		rest := key[len(prefix):]
+		idx := strings.Index(rest, "].")
+		if idx == -1 {
+			continue
+		}
+		n, err := strconv.Atoi(rest[0:idx])
+		if err != nil || n < 0 {
+			continue
+		}
+		if values[n] == nil {
+			values[n] = make(map[string]string)
+		}
+		values[n][rest[idx+2:]] = vals[0]
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	elemFields, err := s.getSchemaFields(schemaKey{typ: field.typ.Elem(), tag: s.tagName})
+	if err != nil {
+		return err
+	}
+
+	indexes := make([]int, 0, len(values))
+	for n := range values {
+		indexes = append(indexes, n)
+	}
+	sort.Ints(indexes)
+
+	slice := reflect.MakeSlice(field.typ, len(indexes), len(indexes))
+	for k, n := range indexes {
+		elem := slice.Index(k)
+		for _, elemField := range elemFields {
+			if formval := values[n][elemField.name]; formval != "" {
+				if err := setFormValue(settableField(elem, elemField.index), formval); err != nil {
+					name := fmt.Sprintf("%s[%d].%s", field.name, n, elemField.name)
+					return bindError(t, name, elemField.typ, formval, err)
+				}
+			}
+		}
+	}
+	settableField(val, field.index).Set(slice)
+	return nil
+}
+
+// SetDisallowUnknownFields rejects form bodies with keys that do not name a
+// schema field with http.StatusBadRequest. The reserved list parameters, such
+// as sort and expand, are always accepted. Unknown keys are ignored by default.
+func (s *Server) SetDisallowUnknownFields(disallow bool) {
+	s.disallowUnknownFields = disallow
+}
+
+// reservedParams are the names of the query parameters read by the server
+// itself, before the prefix set with WithParamPrefix.
+var reservedParams = []string{"sort", "expand", "include", "offset", "limit", "cursor", "format"}
+
+// param returns the query parameter name of the reserved parameter name.
+func (s *Server) param(name string) string {
+	return s.paramPrefix + name
+}
+
+// isReservedParam reports whether key is the name of a reserved parameter.
+func (s *Server) isReservedParam(key string) bool {
+	for _, name := range reservedParams {
+		if key == s.param(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkFormKeys returns an error naming the keys of form that are neither a
+// schema field, an indexed key of a slice of structs nor a reserved parameter.
+func (s *Server) checkFormKeys(form url.Values, fields []formField) error {
+	var unknown []string
+	for key := range form {
+		if !s.isReservedParam(key) && !isFormKey(key, fields) {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+
+	message := fmt.Sprintf("Unknown field %q", unknown[0])
+	if len(unknown) > 1 {
+		quoted := make([]string, len(unknown))
+		for k, key := range unknown {
+			quoted[k] = strconv.Quote(key)
+		}
+		message = "Unknown fields " + strings.Join(quoted, ", ")
+	}
+	return &APIError{
+		Status:  http.StatusBadRequest,
+		Code:    "unknown_field",
+		Message: message,
+		Field:   unknown[0],
+	}
+}
+
+// isFormKey reports whether key names one of fields.
+func isFormKey(key string, fields []formField) bool {
+	for _, field := range fields {
+		if isStructSlice(field.typ) {
+			if strings.HasPrefix(key, field.name+"[") {
+				return true
+			}
+		} else if key == field.name {
+			return true
+		}
+	}
+	return false
+}
+
+// SetStrictSchemas makes Add panic when a schema has a field that form bodies
+// cannot be bound to, such as a map, channel or interface, instead of leaving
+// the field silently unset. Fields implementing encoding.TextUnmarshaler are
+// always supported.
+func (s *Server) SetStrictSchemas(strict bool) {
+	s.strictSchemas = strict
+}
+
+// checkSchemaFields returns an error naming the first schema field that
+// parseForm cannot bind.
+func (s *Server) checkSchemaFields(schema interface{}) error {
+	t := reflect.TypeOf(schema)
+	if t.Kind() == reflect.Map {
+		if t.Key().Kind() != reflect.String {
+			return fmt.Errorf("reason: map schema %s must have string keys", t)
+		}
+		if t.Elem().Kind() != reflect.Interface && !formBindable(t.Elem()) {
+			return fmt.Errorf("reason: map schema %s has unsupported element kind %s", t, t.Elem().Kind())
+		}
+		return nil
+	}
+
+	fields, err := s.getSchemaFields(schemaKey{typ: t, tag: s.tagName})
+	if err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		if !formBindable(field.typ) {
+			return fmt.Errorf("reason: field %q of %s has unsupported kind %s", field.name, t, field.typ.Kind())
+		}
+	}
+	return nil
+}
+
+// formBindable reports whether parseForm can set a field of type t.
+func formBindable(t reflect.Type) bool {
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	if isStructSlice(t) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
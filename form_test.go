@@ -0,0 +1,326 @@
+package reason
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type TimedResource struct {
+	ID      int64     `json:"id"`
+	Created time.Time `json:"created"`
+}
+
+type MappedResource struct {
+	ID   int64             `json:"id"`
+	Meta map[string]string `json:"meta"`
+}
+
+func TestStrictSchemas(t *testing.T) {
+	var schemas = []struct {
+		Schema interface{}
+		Panic  string
+	}{
+		{TestResource{}, ""},
+		{TimedResource{}, ""},
+		{MappedResource{}, `reason: field "meta" of reason.MappedResource has unsupported kind map`},
+	}
+
+	for _, schema := range schemas {
+		func() {
+			defer func() {
+				var msg string
+				if r := recover(); r != nil {
+					msg = r.(error).Error()
+				}
+				if msg != schema.Panic {
+					t.Errorf("%T: expected panic '%s', got '%s'", schema.Schema, schema.Panic, msg)
+				}
+			}()
+
+			s := New()
+			s.SetStrictSchemas(true)
+			s.Add(schema.Schema, NewMapStore("strict"))
+		}()
+	}
+
+	s := New()
+	s.Add(MappedResource{}, NewMapStore("lenient"))
+}
+
+func TestTextUnmarshalerField(t *testing.T) {
+	s := New()
+	s.Add(TimedResource{}, NewMapStore("timed"))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	var requests = []struct {
+		Created    string
+		StatusCode int
+	}{
+		{"2020-01-02T03:04:05Z", 201},
+		{"yesterday", 400},
+	}
+
+	for _, request := range requests {
+		data := url.Values{}
+		data.Add("created", request.Created)
+		res, err := http.PostForm(ts.URL+"/timed", data)
+		if err != nil {
+			t.Fatalf("%s: expected no error from request, got %s", request.Created, err.Error())
+		}
+		res.Body.Close()
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Created, request.StatusCode, res.StatusCode)
+		}
+	}
+
+	res, err := http.Get(ts.URL + "/timed/1")
+	if err != nil {
+		t.Fatalf("expected no error from request, got %s", err.Error())
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("expected no error from read, got %s", err.Error())
+	}
+	if string(body) != `{"id":1,"created":"2020-01-02T03:04:05Z"}` {
+		t.Errorf("expected body '%s', got '%s'", `{"id":1,"created":"2020-01-02T03:04:05Z"}`, body)
+	}
+}
+
+type OrderItem struct {
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+}
+
+type OrderResource struct {
+	ID    int64       `json:"id"`
+	Items []OrderItem `json:"items"`
+}
+
+func TestIndexedFormFields(t *testing.T) {
+	var requests = []struct {
+		Data       string
+		StatusCode int
+		Body       string
+	}{
+		{"items[0].name=a&items[0].quantity=2&items[1].name=b", 201, `{"id":1,"items":[{"name":"a","quantity":2},{"name":"b","quantity":0}]}`},
+		{"items[5].name=later&items[2].name=earlier", 201, `{"id":2,"items":[{"name":"earlier","quantity":0},{"name":"later","quantity":0}]}`},
+		{"items[x].name=a&items[0]name=b&items[-1].name=c", 201, `{"id":3,"items":null}`},
+		{"items[0].quantity=many", 400, `{"error":{"status":400,"code":"invalid_body","message":"OrderResource: field \"items[0].quantity\": invalid int \"many\"","field":"items[0].quantity"}}`},
+	}
+
+	s := New()
+	s.SetStrictSchemas(true)
+	s.Add(OrderResource{}, NewMapStore("orders"))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		res, err := http.Post(ts.URL+"/orders", "application/x-www-form-urlencoded", strings.NewReader(request.Data))
+		if err != nil {
+			t.Fatalf("%s: expected no error from request, got %s", request.Data, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Data, request.StatusCode, res.StatusCode)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Data, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Data, request.Body, body)
+		}
+	}
+}
+
+func TestDisallowUnknownFields(t *testing.T) {
+	var requests = []struct {
+		Path       string
+		Data       string
+		StatusCode int
+		Body       string
+	}{
+		{"/test", "name=Known", 201, `{"id":3,"name":"Known"}`},
+		{"/test?expand=owner", "name=Reserved", 201, `{"id":3,"name":"Reserved"}`},
+		{"/test", "name=Unknown&color=red", 400, `{"error":{"status":400,"code":"unknown_field","message":"Unknown field \"color\"","field":"color"}}`},
+		{"/test?size=1", "name=Unknown&color=red", 400, `{"error":{"status":400,"code":"unknown_field","message":"Unknown fields \"color\", \"size\"","field":"color"}}`},
+		{"/strict_orders", "items[0].name=a", 201, `{"id":1,"items":[{"name":"a","quantity":0}]}`},
+	}
+
+	s := New()
+	s.SetDisallowUnknownFields(true)
+	s.Add(TestResource{}, TestResourceHandler{})
+	s.Add(OrderResource{}, NewMapStore("strict_orders"))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		res, err := http.Post(ts.URL+request.Path, "application/x-www-form-urlencoded", strings.NewReader(request.Data))
+		if err != nil {
+			t.Fatalf("%s: expected no error from request, got %s", request.Data, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Data, request.StatusCode, res.StatusCode)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Data, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Data, request.Body, body)
+		}
+	}
+}
+
+type AuditedResource struct {
+	ID      int64  `json:"id"`
+	Name    string `json:"name"`
+	Created string `json:"created" reason:"immutable"`
+}
+
+func TestImmutableFields(t *testing.T) {
+	var requests = []struct {
+		Path        string
+		ContentType string
+		Data        string
+		Body        string
+	}{
+		{"/audited", "application/x-www-form-urlencoded", "name=First&created=monday", `{"id":1,"name":"First","created":"monday"}`},
+		{"/audited/1", "application/x-www-form-urlencoded", "name=Second&created=tuesday", `{"id":1,"name":"Second","created":"monday"}`},
+		{"/audited/1", "application/json", `{"name":"Third","created":"wednesday"}`, `{"id":1,"name":"Third","created":"monday"}`},
+	}
+
+	s := New()
+	s.Add(AuditedResource{}, NewMapStore("audited"))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		res, err := http.Post(ts.URL+request.Path, request.ContentType, strings.NewReader(request.Data))
+		if err != nil {
+			t.Fatalf("%s: expected no error from request, got %s", request.Data, err.Error())
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Data, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Data, request.Body, body)
+		}
+	}
+}
+
+type DocumentHandler struct{}
+
+func (dh DocumentHandler) Path() string {
+	return "documents"
+}
+
+func (dh DocumentHandler) CreateResource(resource interface{}) (interface{}, error) {
+	doc := resource.(map[string]interface{})
+	doc["id"] = "doc"
+	return doc, nil
+}
+
+func TestMapSchema(t *testing.T) {
+	var requests = []struct {
+		ContentType string
+		Data        string
+		StatusCode  int
+		Body        string
+	}{
+		{"application/x-www-form-urlencoded", "title=Notes&tag=a&tag=b", 201, `{"id":"doc","tag":["a","b"],"title":"Notes"}`},
+		{"application/json", `{"title":"Notes","pages":3}`, 201, `{"id":"doc","pages":3,"title":"Notes"}`},
+	}
+
+	s := New()
+	s.SetStrictSchemas(true)
+	s.Add(map[string]interface{}{}, DocumentHandler{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		res, err := http.Post(ts.URL+"/documents?expand=x", request.ContentType, strings.NewReader(request.Data))
+		if err != nil {
+			t.Fatalf("%s: expected no error from request, got %s", request.Data, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Data, request.StatusCode, res.StatusCode)
+		}
+		if location := res.Header.Get("Location"); location != "/documents/doc" {
+			t.Errorf("%s: expected Location '/documents/doc', got '%s'", request.Data, location)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Data, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Data, request.Body, body)
+		}
+	}
+
+	form, err := s.parseFormMap(url.Values{"a": {"1"}, "b": {"x"}}, reflect.TypeOf(map[string]int{}))
+	if err == nil || err.Error() != `map[string]int: field "b": invalid int "x"` {
+		t.Errorf("expected error binding 'x' to int, got %v (%v)", err, form)
+	}
+}
+
+type NumericResource struct {
+	Count int     `json:"count"`
+	Size  uint    `json:"size"`
+	Ratio float64 `json:"ratio"`
+}
+
+func TestFormBindError(t *testing.T) {
+	var requests = []struct {
+		Data    string
+		Field   string
+		Message string
+	}{
+		{"count=many", "count", `NumericResource: field "count": invalid int "many"`},
+		{"size=-1", "size", `NumericResource: field "size": invalid uint "-1"`},
+		{"ratio=half", "ratio", `NumericResource: field "ratio": invalid float64 "half"`},
+	}
+
+	s := New()
+	for _, request := range requests {
+		r := httptest.NewRequest("POST", "/numeric", strings.NewReader(request.Data))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		_, err := s.parseForm(r, &registration{schema: NumericResource{}})
+		if status := errorStatus(err); status != http.StatusBadRequest {
+			t.Errorf("%s: expected status code 400, got %d", request.Data, status)
+		}
+		apiErr, ok := asAPIError(err)
+		if !ok {
+			t.Fatalf("%s: expected an APIError, got %v", request.Data, err)
+		}
+		if apiErr.Field != request.Field || apiErr.Message != request.Message {
+			t.Errorf("%s: expected field %q and message %q, got %q and %q", request.Data, request.Field, request.Message, apiErr.Field, apiErr.Message)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package reason
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// encoder writes a resource representation in a media type.
+type encoder func(w io.Writer, v interface{}) error
+
+func encodeJSON(w io.Writer, v interface{}) error {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// RegisterEncoder sets the function resources are written with in mediaType,
+// such as "application/xml", adding it to the media types clients can
+// negotiate with the Accept header. The function is given the representation
+// of a resource or the list of representations of a list, and writes the
+// whole body. Registering "application/json" replaces the default encoder.
+//
+// Clients that cannot set an Accept header, such as browsers following links,
+// can select a media type with the format query parameter instead, by its
+// subtype or in full, such as ?format=xml or ?format=application/xml. The
+// parameter takes precedence over the Accept header.
+func (s *Server) RegisterEncoder(mediaType string, fn func(w io.Writer, v interface{}) error) {
+	mediaType = strings.ToLower(mediaType)
+	if _, ok := s.encoders[mediaType]; !ok {
+		s.mediaTypes = append(s.mediaTypes, mediaType)
+	}
+	s.encoders[mediaType] = fn
+}
+
+// WithFormats limits the media types the resource is written in, such as
+// "application/json" and "text/csv", instead of every media type the server
+// can encode. The first media type is used when the client accepts any. Media
+// types without an encoder, such as the type of a Download served by the
+// resource, take part in negotiation but resources are written to them as
+// JSON.
+func WithFormats(mediaTypes ...string) ResourceOption {
+	return func(reg *registration) {
+		reg.formats = make([]string, len(mediaTypes))
+		for k, mediaType := range mediaTypes {
+			reg.formats[k] = strings.ToLower(mediaType)
+		}
+	}
+}
+
+// SetEnvelope wraps JSON resources and lists written to the client in an
+// object under "data", such as {"data":{"id":1}}, so that clients unwrap every
+// response the same way. Errors keep their own envelope under "error".
+func (s *Server) SetEnvelope(enabled bool) {
+	s.envelope = enabled
+}
+
+// SetAPIVersion adds the version of the API contract to the envelope of
+// resources and lists, such as {"apiVersion":"2","data":{"id":1}}, so that
+// clients can detect which contract they are talking to. The version is only
+// written with SetEnvelope.
+func (s *Server) SetAPIVersion(version string) {
+	s.apiVersion = version
+}
+
+type dataEnvelope struct {
+	APIVersion string      `json:"apiVersion,omitempty"`
+	Data       interface{} `json:"data"`
+}
+
+// formatFromContext returns the media type negotiated for a request.
+func formatFromContext(ctx context.Context) string {
+	mediaType, _ := ctx.Value(formatKey).(string)
+	return mediaType
+}
+
+type mediaRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+// matches returns the specificity with which the range matches mediaType, or
+// -1 if it does not match.
+func (mr mediaRange) matches(mediaType string) int {
+	typ, subtype := mediaType, ""
+	if idx := strings.Index(mediaType, "/"); idx != -1 {
+		typ, subtype = mediaType[0:idx], mediaType[idx+1:]
+	}
+	switch {
+	case mr.typ == "*" && mr.subtype == "*":
+		return 0
+	case mr.typ == typ && mr.subtype == "*":
+		return 1
+	case mr.typ == typ && mr.subtype == subtype:
+		return 2
+	}
+	return -1
+}
+
+// negotiateFormat picks the media type a resource is written in from the
+// format query parameter or the Accept header. It returns false when the
+// client accepts none of the media types supported by the resource.
+func (s *Server) negotiateFormat(r *http.Request, reg *registration) (string, bool) {
+	supported := s.formats(reg)
+
+	if format := r.URL.Query().Get(s.param("format")); format != "" {
+		return formatMediaType(strings.ToLower(format), supported)
+	}
+
+	header := r.Header.Get("Accept")
+	if header == "" {
+		return supported[0], true
+	}
+
+	var ranges []mediaRange
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		mr := mediaRange{typ: mediaType, q: 1}
+		if idx := strings.Index(mediaType, "/"); idx != -1 {
+			mr.typ, mr.subtype = mediaType[0:idx], mediaType[idx+1:]
+		}
+		if q, ok := params["q"]; ok {
+			if mr.q, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, mr)
+	}
+
+	// The quality of a media type is that of the most specific range
+	// matching it
+	best, bestQ := "", 0.0
+	for _, mediaType := range supported {
+		specificity, q := -1, 0.0
+		for _, mr := range ranges {
+			if n := mr.matches(mediaType); n > specificity {
+				specificity, q = n, mr.q
+			}
+		}
+		if q > bestQ {
+			best, bestQ = mediaType, q
+		}
+	}
+	if best == "" && len(ranges) > 0 {
+		return "", false
+	}
+	if best == "" {
+		return supported[0], true
+	}
+	return best, true
+}
+
+// formatMediaType returns the supported media type named by a format query
+// parameter, either in full or by its subtype.
+func formatMediaType(format string, supported []string) (string, bool) {
+	for _, mediaType := range supported {
+		if mediaType == format || mediaType[strings.Index(mediaType, "/")+1:] == format {
+			return mediaType, true
+		}
+	}
+	return "", false
+}
+
+// notAcceptable returns the error for a request accepting none of the media
+// types the resource is written in.
+func (s *Server) notAcceptable(reg *registration) error {
+	return &APIError{
+		Status:  http.StatusNotAcceptable,
+		Code:    "not_acceptable",
+		Message: "Supported media types are " + strings.Join(s.formats(reg), ", "),
+	}
+}
+
+// formats returns the media types the resource of reg is written in.
+func (s *Server) formats(reg *registration) []string {
+	if reg.formats != nil {
+		return reg.formats
+	}
+	return s.mediaTypes
+}
+
+// encodeResource encodes res in the media type negotiated for the request,
+// returning the encoded body and its media type.
+func (s *Server) encodeResource(r *http.Request, res interface{}) ([]byte, string, error) {
+	mediaType := formatFromContext(r.Context())
+	enc, ok := s.encoders[mediaType]
+	if !ok {
+		mediaType = s.mediaTypes[0]
+		enc = s.encoders[mediaType]
+	}
+
+	if s.envelope && isJSONMediaType(mediaType) {
+		res = dataEnvelope{s.apiVersion, res}
+	}
+
+	var buf bytes.Buffer
+	if err := enc(&buf, res); err != nil {
+		return nil, mediaType, err
+	}
+	return buf.Bytes(), mediaType, nil
+}
+
+// SetCharset sets the charset declared in the Content-Type of JSON and XML
+// responses, such as application/json; charset=utf-8, for clients that check
+// it. The charset is utf-8 by default, and an empty charset omits it.
+func (s *Server) SetCharset(charset string) {
+	s.charset = charset
+}
+
+// contentType returns the Content-Type header of a response in mediaType,
+// declaring the charset of JSON and XML media types.
+func (s *Server) contentType(mediaType string) string {
+	if s.charset == "" || !hasCharset(mediaType) {
+		return mediaType
+	}
+	return mediaType + "; charset=" + s.charset
+}
+
+// hasCharset reports whether mediaType is a JSON or XML media type, which are
+// written as text.
+func hasCharset(mediaType string) bool {
+	if strings.Contains(mediaType, ";") {
+		return false
+	}
+	slash := strings.IndexByte(mediaType, '/')
+	if slash < 0 {
+		return false
+	}
+	subtype := mediaType[slash+1:]
+	return subtype == "json" || subtype == "xml" || strings.HasSuffix(subtype, "+json") || strings.HasSuffix(subtype, "+xml")
+}
@@ -0,0 +1,258 @@
+package reason
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func encodeTestCSV(w io.Writer, v interface{}) error {
+	list, ok := v.([]interface{})
+	if !ok {
+		list = []interface{}{v}
+	}
+
+	cw := csv.NewWriter(w)
+	for _, item := range list {
+		tr, ok := item.(TestResource)
+		if !ok {
+			return fmt.Errorf("cannot encode %T as CSV", item)
+		}
+		cw.Write([]string{fmt.Sprint(tr.ID), tr.Name})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func TestWithFormats(t *testing.T) {
+	var requests = []struct {
+		Path        string
+		Accept      string
+		StatusCode  int
+		ContentType string
+		Body        string
+	}{
+		{"/export/test", "", 200, "application/json; charset=utf-8", `[{"id":1,"name":"The Test"},{"id":2,"name":"The Other"}]`},
+		{"/export/test", "text/csv", 200, "text/csv", "1,The Test\n2,The Other\n"},
+		{"/export/test/1", "text/*", 200, "text/csv", "1,The Test\n"},
+		{"/export/test/1", "application/json;q=0.5, text/csv", 200, "text/csv", "1,The Test\n"},
+		{"/export/test/1", "*/*;q=0.1, application/json", 200, "application/json; charset=utf-8", `{"id":1,"name":"The Test"}`},
+		{"/export/test/1", "application/xml", 406, "", `{"error":{"status":406,"code":"not_acceptable","message":"Supported media types are application/json, text/csv"}}`},
+		{"/json/test/1", "text/csv", 406, "", `{"error":{"status":406,"code":"not_acceptable","message":"Supported media types are application/json"}}`},
+		{"/json/test/1", "text/csv, */*;q=0.1", 200, "application/json; charset=utf-8", `{"id":1,"name":"The Test"}`},
+		{"/test/1", "application/xml", 406, "", `{"error":{"status":406,"code":"not_acceptable","message":"Supported media types are application/json, text/csv"}}`},
+		{"/test/1", "application/xml, */*;q=0.1", 200, "application/json; charset=utf-8", `{"id":1,"name":"The Test"}`},
+		{"/test/1", "application/*", 200, "application/json; charset=utf-8", `{"id":1,"name":"The Test"}`},
+		{"/test/1", "application/json;q=0, text/*", 200, "text/csv", "1,The Test\n"},
+		{"/test/1", "application/json;q=0", 406, "", `{"error":{"status":406,"code":"not_acceptable","message":"Supported media types are application/json, text/csv"}}`},
+		{"/export/test?format=csv", "application/json", 200, "text/csv", "1,The Test\n2,The Other\n"},
+		{"/test/1?format=Application/JSON", "text/csv", 200, "application/json; charset=utf-8", `{"id":1,"name":"The Test"}`},
+		{"/json/test/1?format=csv", "", 406, "", `{"error":{"status":406,"code":"not_acceptable","message":"Supported media types are application/json"}}`},
+		{"/test/1?format=xml", "", 406, "", `{"error":{"status":406,"code":"not_acceptable","message":"Supported media types are application/json, text/csv"}}`},
+	}
+
+	s := New()
+	s.RegisterEncoder("text/csv", encodeTestCSV)
+	s.Add(TestResource{}, TestResourceHandler{}, WithVersion("export"), WithFormats("application/json", "text/csv"))
+	s.Add(TestResource{}, TestResourceHandler{}, WithVersion("json"), WithFormats("application/json"))
+	s.Add(TestResource{}, TestResourceHandler{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		req, err := http.NewRequest("GET", ts.URL+request.Path, nil)
+		if err != nil {
+			t.Fatalf("%s: expected no error from http.NewRequest, got %s", request.Path, err.Error())
+		}
+		if request.Accept != "" {
+			req.Header.Set("Accept", request.Accept)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: expected no error from request, got %s", request.Path, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s (Accept %s): expected status code %d, got %d", request.Path, request.Accept, request.StatusCode, res.StatusCode)
+		}
+		if request.ContentType != "" && res.Header.Get("Content-Type") != request.ContentType {
+			t.Errorf("%s (Accept %s): expected Content-Type '%s', got '%s'", request.Path, request.Accept, request.ContentType, res.Header.Get("Content-Type"))
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Path, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s (Accept %s): expected body '%s', got '%s'", request.Path, request.Accept, request.Body, body)
+		}
+	}
+}
+
+func TestSetEnvelope(t *testing.T) {
+	var requests = []struct {
+		Path       string
+		StatusCode int
+		Body       string
+	}{
+		{"/test/1", 200, `{"data":{"id":1,"name":"The Test"}}`},
+		{"/test", 200, `{"data":[{"id":1,"name":"The Test"},{"id":2,"name":"The Other"}]}`},
+		{"/nil", 200, `{"data":[]}`},
+		{"/paged?offset=-1", 400, `{"error":{"status":400,"code":"invalid_page","message":"Invalid offset \"-1\"","field":"offset"}}`},
+	}
+
+	s := New()
+	s.SetEnvelope(true)
+	s.Add(TestResource{}, TestResourceHandler{})
+	s.Add(TestResource{}, NilListHandler{})
+	s.Add(TestResource{}, PagedHandler{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		res, err := http.Get(ts.URL + request.Path)
+		if err != nil {
+			t.Fatalf("%s: expected no error from request, got %s", request.Path, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Path, request.StatusCode, res.StatusCode)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Path, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Path, request.Body, body)
+		}
+	}
+}
+
+func TestSetAPIVersion(t *testing.T) {
+	var requests = []struct {
+		Envelope bool
+		Version  string
+		Path     string
+		Body     string
+	}{
+		{true, "2", "/test/1", `{"apiVersion":"2","data":{"id":1,"name":"The Test"}}`},
+		{true, "2", "/test", `{"apiVersion":"2","data":[{"id":1,"name":"The Test"},{"id":2,"name":"The Other"}]}`},
+		{true, "", "/test/1", `{"data":{"id":1,"name":"The Test"}}`},
+		{false, "2", "/test/1", `{"id":1,"name":"The Test"}`},
+	}
+
+	for _, request := range requests {
+		s := New()
+		s.SetEnvelope(request.Envelope)
+		s.SetAPIVersion(request.Version)
+		s.Add(TestResource{}, TestResourceHandler{})
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", request.Path, nil))
+
+		if body := w.Body.String(); body != request.Body {
+			t.Errorf("%s (envelope %t, version %q): expected body '%s', got '%s'", request.Path, request.Envelope, request.Version, request.Body, body)
+		}
+	}
+}
+
+func TestRegisterEncoder(t *testing.T) {
+	s := New()
+	s.RegisterEncoder("Application/JSON", func(w io.Writer, v interface{}) error {
+		_, err := fmt.Fprintf(w, "%v", v)
+		return err
+	})
+	s.Add(TestResource{}, TestResourceHandler{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	if len(s.mediaTypes) != 1 {
+		t.Errorf("expected the JSON encoder to be replaced, got media types %v", s.mediaTypes)
+	}
+
+	res, err := http.Get(ts.URL + "/test/1")
+	if err != nil {
+		t.Fatalf("expected no error from request, got %s", err.Error())
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatalf("expected no error from read, got %s", err.Error())
+	}
+
+	if contentType := res.Header.Get("Content-Type"); contentType != "application/json; charset=utf-8" {
+		t.Errorf("expected Content-Type 'application/json; charset=utf-8', got '%s'", contentType)
+	}
+	if string(body) != "{1 The Test}" {
+		t.Errorf("expected body '{1 The Test}', got '%s'", body)
+	}
+}
+
+func TestSetCharset(t *testing.T) {
+	var requests = []struct {
+		Charset     string
+		Accept      string
+		ContentType string
+	}{
+		{"iso-8859-1", "", "application/json; charset=iso-8859-1"},
+		{"", "", "application/json"},
+		{"iso-8859-1", "application/xml", "application/xml; charset=iso-8859-1"},
+		{"iso-8859-1", "text/csv", "text/csv"},
+	}
+
+	for _, request := range requests {
+		s := New()
+		s.SetCharset(request.Charset)
+		s.RegisterEncoder("application/xml", encodeTestCSV)
+		s.RegisterEncoder("text/csv", encodeTestCSV)
+		s.Add(TestResource{}, TestResourceHandler{})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/test/1", nil)
+		r.Header.Set("Accept", request.Accept)
+		s.ServeHTTP(w, r)
+
+		if contentType := w.Header().Get("Content-Type"); contentType != request.ContentType {
+			t.Errorf("%q (Accept %s): expected Content-Type '%s', got '%s'", request.Charset, request.Accept, request.ContentType, contentType)
+		}
+	}
+}
+
+func TestContentType(t *testing.T) {
+	var requests = []struct {
+		Method      string
+		Path        string
+		StatusCode  int
+		ContentType string
+	}{
+		{"GET", "/test/1", 200, "application/json; charset=utf-8"},
+		{"GET", "/test", 200, "application/json; charset=utf-8"},
+		{"GET", "/paged?offset=-1", 400, "application/json; charset=utf-8"},
+		{"PATCH", "/test", 405, "application/json; charset=utf-8"},
+	}
+
+	s := New()
+	s.Add(TestResource{}, TestResourceHandler{})
+	s.Add(TestResource{}, PagedHandler{})
+
+	for _, request := range requests {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(request.Method, request.Path, nil))
+
+		if w.Code != request.StatusCode {
+			t.Errorf("%s %s: expected status code %d, got %d", request.Method, request.Path, request.StatusCode, w.Code)
+		}
+		if contentType := w.Header().Get("Content-Type"); contentType != request.ContentType {
+			t.Errorf("%s %s: expected Content-Type '%s', got '%s'", request.Method, request.Path, request.ContentType, contentType)
+		}
+	}
+}
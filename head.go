@@ -0,0 +1,35 @@
+package reason
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// head answers HEAD requests with the status and headers fn writes for the
+// GET request, such as the ETag and the Content-Range of a page, without the
+// body. The resource or list is still fetched and encoded, so the headers are
+// the same as those of the GET request.
+func head(fn httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		fn(&headWriter{ResponseWriter: w}, r, ps)
+	}
+}
+
+// headWriter discards the body written to a ResponseWriter. The first write
+// is passed on empty, so that the writer it wraps, such as a compressWriter,
+// sets the headers it would for the body.
+type headWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (hw *headWriter) Write(p []byte) (int, error) {
+	if !hw.written {
+		hw.written = true
+		if _, err := hw.ResponseWriter.Write(nil); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
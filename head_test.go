@@ -0,0 +1,60 @@
+package reason
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHead(t *testing.T) {
+	var requests = []struct {
+		Path       string
+		Range      string
+		Encoding   string
+		StatusCode int
+	}{
+		{"/paged", "items=0-0", "", 206},
+		{"/paged", "items=5-9", "", 416},
+		{"/paged", "", "", 200},
+		{"/paged", "", "gzip", 200},
+		{"/test/1", "", "", 200},
+		{"/test/1", "", "gzip", 200},
+		{"/test/3", "", "", 404},
+	}
+
+	s := New()
+	s.SetCompression(true)
+	s.Add(TestResource{}, PagedHandler{})
+	s.Add(TestResource{}, TestResourceHandler{})
+
+	for _, request := range requests {
+		responses := make(map[string]*httptest.ResponseRecorder)
+		for _, method := range []string{"GET", "HEAD"} {
+			r := httptest.NewRequest(method, request.Path, nil)
+			if request.Range != "" {
+				r.Header.Set("Range", request.Range)
+			}
+			if request.Encoding != "" {
+				r.Header.Set("Accept-Encoding", request.Encoding)
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			responses[method] = w
+		}
+		get, head := responses["GET"], responses["HEAD"]
+
+		if head.Code != request.StatusCode {
+			t.Errorf("%s %s: expected status code %d, got %d", request.Path, request.Range, request.StatusCode, head.Code)
+		}
+		for _, name := range []string{"Content-Type", "Content-Range", "Accept-Ranges", "ETag", "Content-Encoding", "Vary"} {
+			if head.Header().Get(name) != get.Header().Get(name) {
+				t.Errorf("%s %s: expected %s '%s', got '%s'", request.Path, request.Range, name, get.Header().Get(name), head.Header().Get(name))
+			}
+		}
+		if encoding := head.Header().Get("Content-Encoding"); encoding != request.Encoding {
+			t.Errorf("%s %s: expected Content-Encoding '%s', got '%s'", request.Path, request.Range, request.Encoding, encoding)
+		}
+		if head.Body.Len() != 0 {
+			t.Errorf("%s %s: expected no body, got '%s'", request.Path, request.Range, head.Body)
+		}
+	}
+}
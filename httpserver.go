@@ -0,0 +1,39 @@
+package reason
+
+import (
+	"net/http"
+	"time"
+)
+
+// Timeouts of the http.Server returned by HTTPServer. Headers must arrive
+// quickly so that slow clients cannot hold connections open, while bodies and
+// responses are given longer for uploads and large lists.
+const (
+	defaultReadHeaderTimeout = 10 * time.Second
+	defaultReadTimeout       = 30 * time.Second
+	defaultWriteTimeout      = 30 * time.Second
+	defaultIdleTimeout       = 2 * time.Minute
+	defaultMaxHeaderBytes    = 1 << 20
+)
+
+// HTTPServer returns an http.Server listening on addr and serving s, with
+// read, write and idle timeouts set so that slow or idle clients cannot tie up
+// connections, which the zero http.Server allows. The server's errors are
+// reported to the logger of s. The fields can be changed before the server is
+// started:
+//
+//	srv := s.HTTPServer(":8080")
+//	srv.WriteTimeout = 2 * time.Minute
+//	log.Fatal(srv.ListenAndServe())
+func (s *Server) HTTPServer(addr string) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           s,
+		ReadHeaderTimeout: defaultReadHeaderTimeout,
+		ReadTimeout:       defaultReadTimeout,
+		WriteTimeout:      defaultWriteTimeout,
+		IdleTimeout:       defaultIdleTimeout,
+		MaxHeaderBytes:    defaultMaxHeaderBytes,
+		ErrorLog:          s.logger,
+	}
+}
@@ -0,0 +1,41 @@
+package reason
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestHTTPServer(t *testing.T) {
+	s := New()
+	s.Add(TestResource{}, TestResourceHandler{})
+	srv := s.HTTPServer("127.0.0.1:0")
+
+	if srv.Handler != s {
+		t.Errorf("expected the server to serve s, got %v", srv.Handler)
+	}
+	if srv.ReadHeaderTimeout == 0 || srv.ReadTimeout == 0 || srv.WriteTimeout == 0 || srv.IdleTimeout == 0 {
+		t.Errorf("expected timeouts to be set, got %+v", srv)
+	}
+
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		t.Fatalf("expected no error from net.Listen, got %s", err.Error())
+	}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	res, err := http.Get("http://" + ln.Addr().String() + "/test/1")
+	if err != nil {
+		t.Fatalf("expected no error from request, got %s", err.Error())
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatalf("expected no error from read, got %s", err.Error())
+	}
+	if string(body) != `{"id":1,"name":"The Test"}` {
+		t.Errorf("expected body '{\"id\":1,\"name\":\"The Test\"}', got '%s'", body)
+	}
+}
@@ -0,0 +1,131 @@
+package reason
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Identifier implementers report their own id, which the server uses to build
+// the Location of created resources. Resources that do not implement it are
+// identified by the field tagged `json:"id"` or named ID, or by the "id" key
+// of map resources.
+type Identifier interface {
+	GetID() string
+}
+
+// resourceID returns the id of a resource, or false when it has none. The id
+// field of resources that do not implement Identifier is cached along with
+// the fields of their schema.
+func (s *Server) resourceID(res interface{}) (string, bool) {
+	if identifier, ok := res.(Identifier); ok {
+		id := identifier.GetID()
+		return id, id != ""
+	}
+
+	val := reflect.Indirect(reflect.ValueOf(res))
+	if val.Kind() == reflect.Map && val.Type().Key().Kind() == reflect.String {
+		id := val.MapIndex(reflect.ValueOf("id").Convert(val.Type().Key()))
+		if !id.IsValid() || id.IsZero() {
+			return "", false
+		}
+		return fmt.Sprint(id.Interface()), true
+	}
+	if val.Kind() != reflect.Struct {
+		return "", false
+	}
+	fields, err := s.getSchemaFields(schemaKey{typ: val.Type()})
+	if err != nil {
+		return "", false
+	}
+	for _, field := range fields {
+		if field.id {
+			id, ok := fieldValue(val, field.index)
+			if !ok || id.IsZero() {
+				return "", false
+			}
+			return formatID(id), true
+		}
+	}
+	return "", false
+}
+
+// SetIDGenerator sets the function generating ids for resources created
+// without one, such as a UUID generator. The id is set on the id field of
+// struct resources before they are validated and passed to the handler, so
+// that resources can be located when the datastore does not assign ids. The
+// id is parsed into the type of the field. Resources created with an id keep
+// it.
+func (s *Server) SetIDGenerator(fn func() string) {
+	s.idGenerator = fn
+}
+
+// generateID returns a copy of data, a parsed resource of the schema of reg,
+// with a generated id when its id field is empty.
+func (s *Server) generateID(reg *registration, data interface{}) (interface{}, error) {
+	t := reflect.TypeOf(reg.schema)
+	if s.idGenerator == nil || t == nil || t.Kind() != reflect.Struct || reflect.TypeOf(data) != t {
+		return data, nil
+	}
+
+	fields, err := s.getSchemaFields(schemaKey{typ: t})
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range fields {
+		if !field.id {
+			continue
+		}
+		val := reflect.New(t).Elem()
+		val.Set(reflect.ValueOf(data))
+		id := settableField(val, field.index)
+		if !id.IsZero() {
+			return data, nil
+		}
+		generated := s.idGenerator()
+		if err := setFormValue(id, generated); err != nil {
+			return nil, fmt.Errorf("reason: generated id %q for %s: %v", generated, t, err)
+		}
+		return val.Interface(), nil
+	}
+	return data, nil
+}
+
+// idFieldIndex finds the id field of a struct type, preferring a field tagged
+// `json:"id"` over a field named ID. Only string and integer fields qualify.
+func idFieldIndex(t reflect.Type) ([]int, bool) {
+	var named []int
+	for i := 0; i < t.NumField(); i++ {
+		sfield := t.Field(i)
+		switch sfield.Type.Kind() {
+		case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		default:
+			continue
+		}
+
+		tag := sfield.Tag.Get("json")
+		if idx := strings.Index(tag, ","); idx != -1 {
+			tag = tag[0:idx]
+		}
+		if tag == "id" {
+			return sfield.Index, true
+		}
+		if sfield.Name == "ID" && named == nil {
+			named = sfield.Index
+		}
+	}
+	return named, named != nil
+}
+
+// formatID formats an id field value as used in resource paths.
+func formatID(id reflect.Value) string {
+	switch id.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(id.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(id.Uint(), 10)
+	}
+	return id.String()
+}
@@ -0,0 +1,136 @@
+package reason
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+type SlugResource struct {
+	ID   int64
+	Slug string
+}
+
+func (sr SlugResource) GetID() string {
+	return sr.Slug
+}
+
+func TestResourceID(t *testing.T) {
+	var resources = []struct {
+		Resource interface{}
+		ID       string
+		OK       bool
+	}{
+		{TestResource{ID: 42}, "42", true},
+		{&TestResource{ID: 42}, "42", true},
+		{TestResource{}, "", false},
+		{SlugResource{ID: 1, Slug: "the-test"}, "the-test", true},
+		{SlugResource{ID: 1}, "", false},
+		{struct{ ID string }{"abc"}, "abc", true},
+		{struct{ ID uint8 }{7}, "7", true},
+		{struct {
+			ID  string
+			Key int `json:"id,omitempty"`
+		}{"abc", 9}, "9", true},
+		{struct{ ID float64 }{1}, "", false},
+		{"the-test", "", false},
+		{nil, "", false},
+	}
+
+	s := New()
+	for _, resource := range resources {
+		id, ok := s.resourceID(resource.Resource)
+		if id != resource.ID || ok != resource.OK {
+			t.Errorf("%#v: expected id '%s' %t, got '%s' %t", resource.Resource, resource.ID, resource.OK, id, ok)
+		}
+	}
+
+	fields, err := s.getSchemaFields(schemaKey{typ: reflect.TypeOf(TestResource{})})
+	if err != nil {
+		t.Fatalf("expected no error from getSchemaFields, got %s", err.Error())
+	}
+	if !fields[0].id || fields[1].id {
+		t.Errorf("expected the id field to be cached, got %#v", fields)
+	}
+}
+
+func TestCreateLocation(t *testing.T) {
+	form := url.Values{}
+	form.Add("name", "New Test")
+
+	var requests = []struct {
+		Path     string
+		Location string
+	}{
+		{"/test", "/test/3"},
+		{"/v1/test", "/v1/test/3"},
+	}
+
+	s := New()
+	s.Add(TestResource{}, TestResourceHandler{})
+	s.Add(TestResource{}, TestResourceHandler{}, WithVersion("v1"))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		res, err := http.PostForm(ts.URL+request.Path, form)
+		if err != nil {
+			t.Errorf("%s: expected no error from PostForm, got %s", request.Path, err.Error())
+		}
+		res.Body.Close()
+
+		if location := res.Header.Get("Location"); location != request.Location {
+			t.Errorf("%s: expected Location '%s', got '%s'", request.Path, request.Location, location)
+		}
+	}
+}
+
+type KeyedResource struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestIDGenerator(t *testing.T) {
+	var requests = []struct {
+		Data     url.Values
+		Location string
+		Body     string
+	}{
+		{url.Values{"name": {"Generated"}}, "/keyed/gen-1", `{"id":"gen-1","name":"Generated"}`},
+		{url.Values{"id": {"given"}, "name": {"Given"}}, "/keyed/given", `{"id":"given","name":"Given"}`},
+		{url.Values{"name": {"Again"}}, "/keyed/gen-2", `{"id":"gen-2","name":"Again"}`},
+	}
+
+	n := 0
+	s := New()
+	s.SetIDGenerator(func() string {
+		n++
+		return "gen-" + strconv.Itoa(n)
+	})
+	s.Add(KeyedResource{}, NewMapStore("keyed"))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		res, err := http.PostForm(ts.URL+"/keyed", request.Data)
+		if err != nil {
+			t.Fatalf("%v: expected no error from PostForm, got %s", request.Data, err.Error())
+		}
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%v: expected no error from read, got %s", request.Data, err.Error())
+		}
+
+		if location := res.Header.Get("Location"); location != request.Location {
+			t.Errorf("%v: expected Location '%s', got '%s'", request.Data, request.Location, location)
+		}
+		if string(body) != request.Body {
+			t.Errorf("%v: expected body '%s', got '%s'", request.Data, request.Body, body)
+		}
+	}
+}
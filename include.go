@@ -0,0 +1,76 @@
+package reason
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// omittedFields returns the JSON names of the optional schema fields that are
+// left out of a list, as they were not requested with the include query
+// parameter. Naming a field that is not optional is rejected with
+// http.StatusBadRequest.
+func (s *Server) omittedFields(r *http.Request, reg *registration) ([]string, error) {
+	t := reg.output
+	if t == nil {
+		t = reflect.TypeOf(reg.schema)
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, nil
+	}
+
+	fields, err := s.getSchemaFields(schemaKey{typ: t})
+	if err != nil {
+		return nil, err
+	}
+
+	optional := make(map[string]bool)
+	for _, field := range fields {
+		if field.optional {
+			optional[field.name] = true
+		}
+	}
+	if len(optional) == 0 {
+		return nil, nil
+	}
+
+	if param := r.URL.Query().Get(s.param("include")); param != "" {
+		for _, name := range strings.Split(param, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if _, ok := optional[name]; !ok {
+				return nil, &APIError{
+					Status:  http.StatusBadRequest,
+					Code:    "invalid_include",
+					Message: fmt.Sprintf("Cannot include %q", name),
+					Field:   s.param("include"),
+				}
+			}
+			optional[name] = false
+		}
+	}
+
+	var omitted []string
+	for _, field := range fields {
+		if optional[field.name] {
+			omitted = append(omitted, field.name)
+		}
+	}
+	return omitted, nil
+}
+
+// omitFields removes the fields named by names from the JSON representation
+// of a resource.
+func omitFields(res interface{}, names []string) (interface{}, error) {
+	obj, err := newJSONObject(res)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		obj.Delete(name)
+	}
+	return obj, nil
+}
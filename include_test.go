@@ -0,0 +1,62 @@
+package reason
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+type DescribedResource struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description" reason:"optional"`
+	Stats       string `json:"stats" reason:"optional"`
+}
+
+func TestIncludeOptionalFields(t *testing.T) {
+	var requests = []struct {
+		Path       string
+		StatusCode int
+		Body       string
+	}{
+		{"/described", 200, `[{"id":1,"name":"First"}]`},
+		{"/described?include=description", 200, `[{"id":1,"name":"First","description":"Long"}]`},
+		{"/described?include=stats,%20description", 200, `[{"id":1,"name":"First","description":"Long","stats":"Heavy"}]`},
+		{"/described?include=name", 400, `{"error":{"status":400,"code":"invalid_include","message":"Cannot include \"name\"","field":"include"}}`},
+		{"/described/1", 200, `{"id":1,"name":"First","description":"Long","stats":"Heavy"}`},
+	}
+
+	s := New()
+	s.Add(DescribedResource{}, NewMapStore("described"))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	res, err := http.PostForm(ts.URL+"/described", url.Values{"name": {"First"}, "description": {"Long"}, "stats": {"Heavy"}})
+	if err != nil {
+		t.Fatalf("expected no error from PostForm, got %s", err.Error())
+	}
+	res.Body.Close()
+
+	for _, request := range requests {
+		res, err := http.Get(ts.URL + request.Path)
+		if err != nil {
+			t.Fatalf("%s: expected no error from request, got %s", request.Path, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Path, request.StatusCode, res.StatusCode)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Path, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Path, request.Body, body)
+		}
+	}
+}
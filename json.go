@@ -0,0 +1,81 @@
+package reason
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// SetDisallowTrailingJSON rejects JSON request bodies that contain anything
+// other than whitespace after the JSON value, such as {"name":"x"} garbage,
+// with http.StatusBadRequest. Trailing data is ignored by default.
+func (s *Server) SetDisallowTrailingJSON(disallow bool) {
+	s.disallowTrailingJSON = disallow
+}
+
+// isJSON reports whether the request body is sent as JSON.
+func isJSON(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return isJSONMediaType(mediaType)
+}
+
+// isJSONMediaType reports whether mediaType is JSON or a JSON based format.
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func (s *Server) parseJSON(r *http.Request, reg *registration) (interface{}, error) {
+	val := reflect.New(reflect.TypeOf(reg.schema))
+
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(val.Interface()); err != nil && err != io.EOF {
+		return nil, jsonError(err)
+	}
+
+	if s.disallowTrailingJSON {
+		if _, err := dec.Token(); err != io.EOF {
+			return nil, &APIError{
+				Status:  http.StatusBadRequest,
+				Code:    "invalid_body",
+				Message: "Unexpected data after JSON body",
+			}
+		}
+	}
+
+	if OperationFromContext(r.Context()) == OperationUpdate && val.Elem().Kind() == reflect.Struct {
+		if err := s.copyImmutableFields(reg, val.Elem(), reflect.Value{}); err != nil {
+			return nil, err
+		}
+	}
+
+	return val.Elem().Interface(), nil
+}
+
+// jsonError converts an error decoding a JSON body to an error for the client.
+func jsonError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return err
+	}
+
+	apiErr := &APIError{
+		Status:  http.StatusBadRequest,
+		Code:    "invalid_body",
+		Message: "Malformed JSON body",
+		Err:     err,
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		apiErr.Message = fmt.Sprintf("Invalid value for field %q", typeErr.Field)
+		apiErr.Field = typeErr.Field
+	}
+	return apiErr
+}
@@ -0,0 +1,86 @@
+package reason
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJSONBody(t *testing.T) {
+	var requests = []struct {
+		Body             string
+		DisallowTrailing bool
+		StatusCode       int
+		Response         string
+	}{
+		{`{"name":"JSON Test"}`, false, 201, `{"id":3,"name":"JSON Test"}`},
+		{`{"name":"JSON Test"}`, true, 201, `{"id":3,"name":"JSON Test"}`},
+		{"{\"name\":\"JSON Test\"} \n", true, 201, `{"id":3,"name":"JSON Test"}`},
+		{``, false, 201, `{"id":3,"name":""}`},
+		{`{"name":"JSON Test"} garbage`, false, 201, `{"id":3,"name":"JSON Test"}`},
+		{`{"name":"JSON Test"} garbage`, true, 400, `{"error":{"status":400,"code":"invalid_body","message":"Unexpected data after JSON body"}}`},
+		{`{"name":"JSON Test"}{"name":"Other"}`, true, 400, `{"error":{"status":400,"code":"invalid_body","message":"Unexpected data after JSON body"}}`},
+		{`{"name":`, false, 400, `{"error":{"status":400,"code":"invalid_body","message":"Malformed JSON body"}}`},
+		{`{"name":1}`, false, 400, `{"error":{"status":400,"code":"invalid_body","message":"Invalid value for field \"name\"","field":"name"}}`},
+	}
+
+	for _, request := range requests {
+		s := New()
+		s.Add(TestResource{}, TestResourceHandler{})
+		s.SetDisallowTrailingJSON(request.DisallowTrailing)
+		ts := httptest.NewServer(s)
+
+		res, err := http.Post(ts.URL+"/test", "application/json", strings.NewReader(request.Body))
+		if err != nil {
+			t.Errorf("%s: expected no error from Post, got %s", request.Body, err.Error())
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		ts.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Body, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Body, request.StatusCode, res.StatusCode)
+		}
+		if string(body) != request.Response {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Body, request.Response, body)
+		}
+	}
+}
+
+func TestJSONUpdate(t *testing.T) {
+	var requests = []struct {
+		ContentType string
+		Body        string
+		StatusCode  int
+		Response    string
+	}{
+		{"application/json", `{"name":"JSON Update"}`, 200, `{"id":1,"name":"JSON Update"}`},
+		{"application/json; charset=utf-8", `{"name":"JSON Update"}`, 200, `{"id":1,"name":"JSON Update"}`},
+		{"application/merge-patch+json", `{"name":"JSON Update"}`, 200, `{"id":1,"name":"JSON Update"}`},
+		{"application/json", `{"name":`, 400, `{"error":{"status":400,"code":"invalid_body","message":"Malformed JSON body"}}`},
+		{"application/x-www-form-urlencoded", `name=Form+Update`, 200, `{"id":1,"name":"Form Update"}`},
+	}
+
+	s := New()
+	s.Add(TestResource{}, TestResourceHandler{})
+
+	for _, request := range requests {
+		r := httptest.NewRequest("POST", "/test/1", strings.NewReader(request.Body))
+		r.Header.Set("Content-Type", request.ContentType)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+
+		if w.Code != request.StatusCode {
+			t.Errorf("%s %s: expected status code %d, got %d", request.ContentType, request.Body, request.StatusCode, w.Code)
+		}
+		if body := w.Body.String(); body != request.Response {
+			t.Errorf("%s %s: expected body '%s', got '%s'", request.ContentType, request.Body, request.Response, body)
+		}
+	}
+}
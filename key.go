@@ -0,0 +1,129 @@
+package reason
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+// KeyGetter implementers can look up a resource by a field other than its id,
+// such as a slug. Resources added WithKey are looked up by key instead of
+// through GetResource.
+type KeyGetter interface {
+	GetResourceByKey(key string, value string) (interface{}, error)
+}
+
+// IntGetter implementers can look up a resource by an integer id. When the id
+// field of the resource schema is an integer, gets, updates and deletes parse
+// the id in the path, answering ids that are not integers of the field's size
+// with http.StatusBadRequest, and look the resource up with GetResourceInt
+// instead of GetResource. The handler must still implement Getter, which
+// other schemas are looked up with.
+type IntGetter interface {
+	GetResourceInt(id int64) (interface{}, error)
+}
+
+// WithKey names the path parameter identifying a resource, so a resource added
+// with WithKey("slug") is served at /path/:slug. When the handler implements
+// KeyGetter, gets, updates and deletes look the resource up by key, otherwise
+// the value is passed to GetResource.
+func WithKey(key string) ResourceOption {
+	return func(reg *registration) {
+		reg.key = key
+	}
+}
+
+// itemValue returns the value of the item route parameter identifying res,
+// given its id. Resources added WithKey are looked up by key when their handler
+// is a KeyGetter, so the value is that of the key field, and false when res has
+// none.
+func (s *Server) itemValue(reg *registration, res interface{}, id string) (string, bool) {
+	if _, ok := reg.handler.(KeyGetter); !ok || reg.key == "" {
+		return id, id != ""
+	}
+
+	val := reflect.Indirect(reflect.ValueOf(res))
+	if val.Kind() == reflect.Map && val.Type().Key().Kind() == reflect.String {
+		key := val.MapIndex(reflect.ValueOf(reg.key).Convert(val.Type().Key()))
+		if !key.IsValid() || key.IsZero() {
+			return "", false
+		}
+		return fmt.Sprint(key.Interface()), true
+	}
+	if val.Kind() != reflect.Struct {
+		return "", false
+	}
+	fields, err := s.getSchemaFields(schemaKey{typ: val.Type()})
+	if err != nil {
+		return "", false
+	}
+	for _, field := range fields {
+		if field.name == reg.key {
+			key, ok := fieldValue(val, field.index)
+			if !ok || key.IsZero() {
+				return "", false
+			}
+			return formatID(key), true
+		}
+	}
+	return "", false
+}
+
+// getResource looks up the resource identified by value. An empty value is
+// rejected without calling the handler, as no route should produce one.
+func (s *Server) getResource(reg *registration, getter Getter, value string) (interface{}, error) {
+	if value == "" {
+		return nil, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    "invalid_id",
+			Message: "Missing id",
+		}
+	}
+
+	var res interface{}
+	var err error
+	if kg, ok := getter.(KeyGetter); ok && reg.key != "" {
+		res, err = kg.GetResourceByKey(reg.key, value)
+	} else if ig, ok := getter.(IntGetter); ok && reg.key == "" && s.intIDBits(reg) > 0 {
+		id, perr := strconv.ParseInt(value, 10, s.intIDBits(reg))
+		if perr != nil {
+			return nil, &APIError{
+				Status:  http.StatusBadRequest,
+				Code:    "invalid_id",
+				Message: fmt.Sprintf("Invalid id %q", value),
+			}
+		}
+		res, err = ig.GetResourceInt(id)
+	} else {
+		res, err = getter.GetResource(value)
+	}
+	if err != nil {
+		return nil, s.describeNotFound(reg, value, err)
+	}
+	return res, nil
+}
+
+// intIDBits returns the size of the id field of the resource schema when it
+// is a signed integer, or 0 otherwise.
+func (s *Server) intIDBits(reg *registration) int {
+	t := reflect.TypeOf(reg.schema)
+	if t == nil || t.Kind() != reflect.Struct {
+		return 0
+	}
+	fields, err := s.getSchemaFields(schemaKey{typ: t})
+	if err != nil {
+		return 0
+	}
+	for _, field := range fields {
+		if !field.id {
+			continue
+		}
+		switch field.typ.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return field.typ.Bits()
+		}
+		return 0
+	}
+	return 0
+}
@@ -0,0 +1,194 @@
+package reason
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type KeyHandler struct {
+	TestResourceHandler
+}
+
+func (kh KeyHandler) Path() string {
+	return "named"
+}
+
+func (kh KeyHandler) GetResourceByKey(key string, value string) (interface{}, error) {
+	if key != "name" {
+		return nil, ErrNotFound
+	}
+	for _, data := range testData {
+		if strings.EqualFold(data.Name, value) {
+			return data, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func TestWithKey(t *testing.T) {
+	var requests = []struct {
+		Path       string
+		StatusCode int
+		Body       string
+	}{
+		{"/named/the%20test", 200, `{"id":1,"name":"The Test"}`},
+		{"/named/The%20Other", 200, `{"id":2,"name":"The Other"}`},
+		{"/named/1", 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`},
+		{"/test/1", 200, `{"id":1,"name":"The Test"}`},
+	}
+
+	s := New()
+	s.Add(TestResource{}, KeyHandler{}, WithKey("name"))
+	s.Add(TestResource{}, TestResourceHandler{}, WithKey("slug"))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		res, err := http.Get(ts.URL + request.Path)
+		if err != nil {
+			t.Fatalf("%s: expected no error from request, got %s", request.Path, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Path, request.StatusCode, res.StatusCode)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Path, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Path, request.Body, body)
+		}
+	}
+}
+
+func TestWithKeyLocation(t *testing.T) {
+	var requests = []struct {
+		Path     string
+		Name     string
+		Location string
+	}{
+		{"/named", "New Test", "/named/New%20Test"},
+		{"/named", "", ""},
+		{"/test", "New Test", "/test/3"},
+	}
+
+	s := New()
+	s.Add(TestResource{}, KeyHandler{}, WithKey("name"))
+	s.Add(TestResource{}, TestResourceHandler{}, WithKey("slug"))
+
+	for _, request := range requests {
+		r := httptest.NewRequest("POST", request.Path, strings.NewReader("name="+url.QueryEscape(request.Name)))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+
+		if w.Code != http.StatusCreated {
+			t.Errorf("%s %q: expected status code 201, got %d", request.Path, request.Name, w.Code)
+		}
+		if location := w.Header().Get("Location"); location != request.Location {
+			t.Errorf("%s %q: expected Location '%s', got '%s'", request.Path, request.Name, request.Location, location)
+		}
+	}
+}
+
+type IntHandler struct {
+	TestResourceHandler
+}
+
+func (ih IntHandler) Path() string {
+	return "ints"
+}
+
+func (ih IntHandler) GetResourceInt(id int64) (interface{}, error) {
+	for _, data := range testData {
+		if data.ID == id {
+			return data, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func TestIntGetter(t *testing.T) {
+	var requests = []struct {
+		Path       string
+		StatusCode int
+		Body       string
+	}{
+		{"/ints/2", 200, `{"id":2,"name":"The Other"}`},
+		{"/ints/3", 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`},
+		{"/ints/two", 400, `{"error":{"status":400,"code":"invalid_id","message":"Invalid id \"two\""}}`},
+		{"/ints/99999999999999999999", 400, `{"error":{"status":400,"code":"invalid_id","message":"Invalid id \"99999999999999999999\""}}`},
+	}
+
+	s := New()
+	s.Add(TestResource{}, IntHandler{})
+
+	for _, request := range requests {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest("GET", request.Path, nil))
+
+		if rec.Code != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Path, request.StatusCode, rec.Code)
+		}
+		if body := rec.Body.String(); body != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Path, request.Body, body)
+		}
+	}
+}
+
+type EmptyIDHandler struct {
+	TestResourceHandler
+	called *bool
+}
+
+func (eh EmptyIDHandler) GetResource(id string) (interface{}, error) {
+	*eh.called = true
+	return eh.TestResourceHandler.GetResource(id)
+}
+
+func TestEmptyID(t *testing.T) {
+	var called bool
+	handler := EmptyIDHandler{called: &called}
+	s := New()
+	s.Add(TestResource{}, handler)
+	reg := s.registrations[0]
+
+	var requests = []struct {
+		Name  string
+		Serve func(w http.ResponseWriter, r *http.Request)
+	}{
+		{"get", func(w http.ResponseWriter, r *http.Request) {
+			s.getRequest(w, r, reg, "", handler)
+		}},
+		{"update", func(w http.ResponseWriter, r *http.Request) {
+			s.updateRequest(w, r, reg, "", handler, TestResource{Name: "New"})
+		}},
+		{"delete", func(w http.ResponseWriter, r *http.Request) {
+			s.deleteRequest(w, r, reg, "", handler)
+		}},
+	}
+
+	for _, request := range requests {
+		called = false
+		rec := httptest.NewRecorder()
+		request.Serve(rec, httptest.NewRequest("GET", "/test/", nil))
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status code 400, got %d", request.Name, rec.Code)
+		}
+		if body, expected := rec.Body.String(), `{"error":{"status":400,"code":"invalid_id","message":"Missing id"}}`; body != expected {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Name, expected, body)
+		}
+		if called {
+			t.Errorf("%s: expected the handler not to be called", request.Name)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package reason
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SetLanguages sets the languages supported by the server, such as "en" or
+// "pt-BR". Each request is matched against the Accept-Language header and the
+// negotiated language is stored in the request context, see
+// LanguageFromContext. The first language is used when nothing matches.
+func (s *Server) SetLanguages(languages ...string) {
+	s.languages = languages
+}
+
+// SetTranslator sets a function used to localize the message of errors
+// written to the client into the negotiated language.
+func (s *Server) SetTranslator(fn func(language string, err *APIError) string) {
+	s.translator = fn
+}
+
+// LanguageFromContext returns the language negotiated for a request, or an
+// empty string when the server has no languages configured.
+func LanguageFromContext(ctx context.Context) string {
+	language, _ := ctx.Value(languageKey).(string)
+	return language
+}
+
+// withLanguage stores the negotiated language in the request context.
+func (s *Server) withLanguage(r *http.Request) *http.Request {
+	if len(s.languages) == 0 {
+		return r
+	}
+	language := matchLanguage(r.Header.Get("Accept-Language"), s.languages)
+	return r.WithContext(context.WithValue(r.Context(), languageKey, language))
+}
+
+type languageRange struct {
+	tag string
+	q   float64
+}
+
+// matchLanguage picks the supported language best matching an Accept-Language
+// header. Exact matches are preferred over matches on the primary subtag, so
+// "en-US" matches "en" and "en" matches "en-GB".
+func matchLanguage(header string, supported []string) string {
+	var ranges []languageRange
+	for _, part := range strings.Split(header, ",") {
+		lr := languageRange{q: 1}
+		if idx := strings.Index(part, ";"); idx != -1 {
+			param := strings.TrimSpace(part[idx+1:])
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil {
+					continue
+				}
+				lr.q = q
+			}
+			part = part[0:idx]
+		}
+		lr.tag = strings.TrimSpace(part)
+		if lr.tag != "" && lr.q > 0 {
+			ranges = append(ranges, lr)
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+
+	for _, lr := range ranges {
+		if lr.tag == "*" {
+			return supported[0]
+		}
+		for _, language := range supported {
+			if strings.EqualFold(lr.tag, language) {
+				return language
+			}
+		}
+		for _, language := range supported {
+			if strings.EqualFold(primarySubtag(lr.tag), primarySubtag(language)) {
+				return language
+			}
+		}
+	}
+	return supported[0]
+}
+
+func primarySubtag(tag string) string {
+	if idx := strings.Index(tag, "-"); idx != -1 {
+		return tag[0:idx]
+	}
+	return tag
+}
@@ -0,0 +1,81 @@
+package reason
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestMatchLanguage(t *testing.T) {
+	supported := []string{"en", "pt-BR", "fr"}
+
+	var requests = []struct {
+		Header   string
+		Language string
+	}{
+		{"", "en"},
+		{"fr", "fr"},
+		{"FR-ca", "fr"},
+		{"pt", "pt-BR"},
+		{"de, pt-br;q=0.5, fr;q=0.8", "fr"},
+		{"fr;q=0, pt-PT", "pt-BR"},
+		{"de;q=0.9, *;q=0.1", "en"},
+		{"de", "en"},
+	}
+
+	for _, request := range requests {
+		if language := matchLanguage(request.Header, supported); language != request.Language {
+			t.Errorf("%s: expected language %s, got %s", request.Header, request.Language, language)
+		}
+	}
+}
+
+func TestTranslator(t *testing.T) {
+	var requests = []struct {
+		AcceptLanguage string
+		Body           string
+	}{
+		{"", `{"error":{"status":409,"code":"conflict","message":"Name is taken","field":"name"}}`},
+		{"fr-FR, en;q=0.5", `{"error":{"status":409,"code":"conflict","message":"Conflit sur name","field":"name"}}`},
+	}
+
+	s := New()
+	s.Add(TestResource{}, ConflictHandler{})
+	s.SetLanguages("en", "fr")
+	s.SetTranslator(func(language string, err *APIError) string {
+		if language == "fr" && err.Code == "conflict" {
+			return "Conflit sur " + err.Field
+		}
+		return err.Message
+	})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		form := url.Values{"name": {"field"}}
+		req, err := http.NewRequest("POST", ts.URL+"/conflict", strings.NewReader(form.Encode()))
+		if err != nil {
+			t.Errorf("%s: expected no error from http.NewRequest, got %s", request.AcceptLanguage, err.Error())
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept-Language", request.AcceptLanguage)
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Errorf("%s: expected no error from client.Do, got %s", request.AcceptLanguage, err.Error())
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.AcceptLanguage, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.AcceptLanguage, request.Body, body)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package reason
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maintenanceRetryAfter is the number of seconds clients are asked to wait
+// before retrying a request refused during maintenance.
+const maintenanceRetryAfter = 60
+
+// SetMaintenance puts the server in or out of maintenance mode. During
+// maintenance every request, except those for the paths allowed with
+// SetMaintenanceAllow, is answered with http.StatusServiceUnavailable, a
+// Retry-After header and an error body. It is safe to call while the server
+// is serving requests.
+func (s *Server) SetMaintenance(enabled bool) {
+	s.maintenance.Store(enabled)
+}
+
+// SetMaintenanceAllow sets the paths served during maintenance, such as
+// "/health". A path also allows the paths below it. It is safe to call while
+// the server is serving requests.
+func (s *Server) SetMaintenanceAllow(paths ...string) {
+	allow := allowList(paths)
+	s.maintenanceAllow.Store(&allow)
+}
+
+// allowList normalizes paths given to SetMaintenanceAllow or SetReadOnlyAllow.
+func allowList(paths []string) []string {
+	allow := make([]string, len(paths))
+	for k, path := range paths {
+		allow[k] = "/" + strings.Trim(path, "/")
+	}
+	return allow
+}
+
+// pathAllowed reports whether path is one of allow or below one of them.
+func pathAllowed(allow []string, path string) bool {
+	for _, prefix := range allow {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkMaintenance reports whether a request may be served, writing the
+// response and returning false when the server is in maintenance and the
+// request path is not allowed.
+func (s *Server) checkMaintenance(w http.ResponseWriter, r *http.Request) bool {
+	if !s.maintenance.Load() {
+		return true
+	}
+	if allow := s.maintenanceAllow.Load(); allow != nil && pathAllowed(*allow, r.URL.Path) {
+		return true
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(maintenanceRetryAfter))
+	s.writeError(w, r, &APIError{
+		Status:  http.StatusServiceUnavailable,
+		Code:    "maintenance",
+		Message: "The service is down for maintenance",
+	})
+	return false
+}
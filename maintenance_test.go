@@ -0,0 +1,67 @@
+package reason
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type HealthHandler struct{}
+
+func (hh HealthHandler) Path() string {
+	return "health"
+}
+
+func (hh HealthHandler) ListResource() ([]interface{}, error) {
+	return []interface{}{}, nil
+}
+
+func TestMaintenance(t *testing.T) {
+	var requests = []struct {
+		Maintenance bool
+		Path        string
+		StatusCode  int
+		RetryAfter  string
+		Body        string
+	}{
+		{false, "/test/1", 200, "", `{"id":1,"name":"The Test"}`},
+		{true, "/test/1", 503, "60", `{"error":{"status":503,"code":"maintenance","message":"The service is down for maintenance"}}`},
+		{true, "/health", 200, "", `[]`},
+		{true, "/healthz", 503, "60", `{"error":{"status":503,"code":"maintenance","message":"The service is down for maintenance"}}`},
+		{false, "/test/1", 200, "", `{"id":1,"name":"The Test"}`},
+	}
+
+	s := New()
+	s.SetMaintenanceAllow("health")
+	s.Add(TestResource{}, TestResourceHandler{})
+	s.Add(TestResource{}, HealthHandler{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		s.SetMaintenance(request.Maintenance)
+
+		res, err := http.Get(ts.URL + request.Path)
+		if err != nil {
+			t.Fatalf("%s: expected no error from request, got %s", request.Path, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Path, request.StatusCode, res.StatusCode)
+		}
+		if retryAfter := res.Header.Get("Retry-After"); retryAfter != request.RetryAfter {
+			t.Errorf("%s: expected Retry-After '%s', got '%s'", request.Path, request.RetryAfter, retryAfter)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Path, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Path, request.Body, body)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package reason
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// jsonObject is a JSON object that keeps the order of its keys, used to add
+// fields to the JSON representation of a resource.
+type jsonObject struct {
+	keys   []string
+	values map[string]json.RawMessage
+}
+
+// newJSONObject marshals v, which must encode to a JSON object.
+func newJSONObject(v interface{}) (*jsonObject, error) {
+	if obj, ok := v.(*jsonObject); ok {
+		return obj, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("reason: %T does not encode to a JSON object", v)
+	}
+
+	obj := &jsonObject{values: make(map[string]json.RawMessage)}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		obj.setRaw(tok.(string), value)
+	}
+	return obj, nil
+}
+
+func (o *jsonObject) setRaw(key string, value json.RawMessage) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// Set adds a field to the object, replacing any existing field with the same
+// key in place.
+func (o *jsonObject) Set(key string, v interface{}) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	o.setRaw(key, value)
+	return nil
+}
+
+// Delete removes the field with key from the object, if it has one.
+func (o *jsonObject) Delete(key string) {
+	if _, ok := o.values[key]; !ok {
+		return
+	}
+	delete(o.values, key)
+	for k, name := range o.keys {
+		if name == key {
+			o.keys = append(o.keys[:k], o.keys[k+1:]...)
+			break
+		}
+	}
+}
+
+func (o *jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for k, key := range o.keys {
+		if k > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(o.values[key])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
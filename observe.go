@@ -0,0 +1,63 @@
+package reason
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// SetRequestObserver calls fn after each request is served, with the template
+// of the route that served it, see RouteFromContext, the response status and
+// the time taken to serve it. Requests that matched no resource route, such as
+// those not found or rate limited, have an empty route. It is meant for
+// metrics, which should be labelled by route rather than by path to keep
+// their cardinality bounded.
+func (s *Server) SetRequestObserver(fn func(r *http.Request, route string, status int, duration time.Duration)) {
+	s.observer = fn
+}
+
+// observeWriter records the status of a response.
+type observeWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (ow *observeWriter) WriteHeader(status int) {
+	if ow.status == 0 {
+		ow.status = status
+	}
+	ow.ResponseWriter.WriteHeader(status)
+}
+
+func (ow *observeWriter) Write(b []byte) (int, error) {
+	if ow.status == 0 {
+		ow.status = http.StatusOK
+	}
+	return ow.ResponseWriter.Write(b)
+}
+
+func (ow *observeWriter) Flush() {
+	if flusher, ok := ow.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// observe wraps w to record the status of the response and returns the
+// function reporting the request to the observer once it is served.
+func (s *Server) observe(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *http.Request, func()) {
+	if s.observer == nil {
+		return w, r, func() {}
+	}
+
+	start := time.Now()
+	route := new(string)
+	ow := &observeWriter{ResponseWriter: w}
+	r = r.WithContext(context.WithValue(r.Context(), observedRouteKey, route))
+	return ow, r, func() {
+		status := ow.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		s.observer(r, *route, status, time.Since(start))
+	}
+}
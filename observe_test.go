@@ -0,0 +1,66 @@
+package reason
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type RouteHandler struct {
+	ctx context.Context
+}
+
+func (rh RouteHandler) Path() string {
+	return "routed"
+}
+
+func (rh RouteHandler) WithContext(ctx context.Context) ResourceHandler {
+	rh.ctx = ctx
+	return rh
+}
+
+func (rh RouteHandler) GetResource(id string) (interface{}, error) {
+	return TestResource{Name: RouteFromContext(rh.ctx)}, nil
+}
+
+func TestRequestObserver(t *testing.T) {
+	var requests = []struct {
+		Method   string
+		Path     string
+		Observed string
+		Body     string
+	}{
+		{"GET", "/routed/42", "/routed/:id 200", `{"id":0,"name":"/routed/:id"}`},
+		{"GET", "/test", "/test 200", ``},
+		{"POST", "/test", "/test 201", ``},
+		{"DELETE", "/test/1", "/test/:id 200", ``},
+		{"GET", "/missing/1", " 404", ``},
+	}
+
+	var observed []string
+	s := New()
+	s.SetRequestObserver(func(r *http.Request, route string, status int, duration time.Duration) {
+		if duration < 0 {
+			t.Errorf("%s %s: expected a duration, got %s", r.Method, r.URL.Path, duration)
+		}
+		observed = append(observed, fmt.Sprintf("%s %d", route, status))
+	})
+	s.Add(TestResource{}, RouteHandler{})
+	s.Add(TestResource{}, TestResourceHandler{})
+
+	for _, request := range requests {
+		observed = nil
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(request.Method, request.Path, nil))
+
+		if fmt.Sprint(observed) != fmt.Sprint([]string{request.Observed}) {
+			t.Errorf("%s %s: expected observed '%s', got %v", request.Method, request.Path, request.Observed, observed)
+		}
+		if request.Body != "" && rec.Body.String() != request.Body {
+			t.Errorf("%s %s: expected body '%s', got '%s'", request.Method, request.Path, request.Body, rec.Body.String())
+		}
+	}
+}
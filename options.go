@@ -0,0 +1,58 @@
+package reason
+
+import (
+	"log"
+	"strings"
+)
+
+// Option configures a Server created with New.
+type Option func(*Server)
+
+// WithBasePath mounts every resource under a base path, so a resource with the
+// path "users" added to a server created with WithBasePath("api") is served at
+// /api/users.
+func WithBasePath(path string) Option {
+	return func(s *Server) {
+		s.basePath = strings.Trim(path, "/")
+	}
+}
+
+// WithTagName names form fields by the given struct tag instead of the json
+// tag. JSON bodies and responses are always named by the json tag.
+func WithTagName(name string) Option {
+	return func(s *Server) {
+		s.tagName = name
+	}
+}
+
+// WithMaxBodyBytes sets the request body limit, see SetMaxBodyBytes.
+func WithMaxBodyBytes(n int64) Option {
+	return func(s *Server) {
+		s.SetMaxBodyBytes(n)
+	}
+}
+
+// WithMaxURLLength sets the URL length limit, see SetMaxURLLength.
+func WithMaxURLLength(n int) Option {
+	return func(s *Server) {
+		s.SetMaxURLLength(n)
+	}
+}
+
+// WithParamPrefix prefixes the names of the query parameters read by the
+// server, such as sort, expand, include, offset, limit and cursor, so that
+// they cannot clash with schema fields filtered on. A server created with
+// WithParamPrefix("_") sorts lists by ?_sort=name.
+func WithParamPrefix(prefix string) Option {
+	return func(s *Server) {
+		s.paramPrefix = prefix
+	}
+}
+
+// WithLogger sets the logger unhandled errors are reported to. The standard
+// logger is used by default.
+func WithLogger(logger *log.Logger) Option {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
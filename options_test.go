@@ -0,0 +1,118 @@
+package reason
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type TaggedResource struct {
+	ID   int64  `json:"id" form:"key"`
+	Name string `json:"name" form:"title"`
+}
+
+func TestOptions(t *testing.T) {
+	var logs bytes.Buffer
+	s := New(
+		WithBasePath("/api/"),
+		WithTagName("form"),
+		WithMaxBodyBytes(32),
+		WithLogger(log.New(&logs, "", 0)),
+	)
+	s.Add(TaggedResource{}, NewMapStore("tagged"))
+	s.Add(TestResource{}, FailingHandler{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	form := url.Values{}
+	form.Add("key", "7")
+	form.Add("title", "Tagged")
+	res, err := http.PostForm(ts.URL+"/api/tagged", form)
+	if err != nil {
+		t.Fatalf("expected no error from request, got %s", err.Error())
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatalf("expected no error from read, got %s", err.Error())
+	}
+
+	if res.StatusCode != 201 {
+		t.Errorf("expected status code 201, got %d", res.StatusCode)
+	}
+	if location := res.Header.Get("Location"); location != "/api/tagged/7" {
+		t.Errorf("expected Location '/api/tagged/7', got '%s'", location)
+	}
+	if string(body) != `{"id":7,"name":"Tagged"}` {
+		t.Errorf("expected body '%s', got '%s'", `{"id":7,"name":"Tagged"}`, body)
+	}
+
+	res, err = http.Post(ts.URL+"/api/tagged", "application/x-www-form-urlencoded", strings.NewReader("title="+strings.Repeat("a", 64)))
+	if err != nil {
+		t.Fatalf("expected no error from request, got %s", err.Error())
+	}
+	res.Body.Close()
+	if res.StatusCode != 413 {
+		t.Errorf("expected status code 413, got %d", res.StatusCode)
+	}
+
+	res, err = http.Get(ts.URL + "/tagged/7")
+	if err != nil {
+		t.Fatalf("expected no error from request, got %s", err.Error())
+	}
+	res.Body.Close()
+	if res.StatusCode != 404 {
+		t.Errorf("expected status code 404 outside the base path, got %d", res.StatusCode)
+	}
+
+	res, err = http.Get(ts.URL + "/api/fail/1")
+	if err != nil {
+		t.Fatalf("expected no error from request, got %s", err.Error())
+	}
+	res.Body.Close()
+	if !strings.Contains(logs.String(), "Unhandled error") {
+		t.Errorf("expected unhandled error to be logged, got '%s'", logs.String())
+	}
+}
+
+func TestParamPrefix(t *testing.T) {
+	var requests = []struct {
+		Query string
+		Link  string
+		Body  string
+	}{
+		{"?_limit=1", `</paged?_limit=1&_offset=1>; rel="next"`, `[{"id":1,"name":"The Test"}]`},
+		{"?limit=1", "", `[{"id":1,"name":"The Test"},{"id":2,"name":"The Other"}]`},
+	}
+
+	s := New(WithParamPrefix("_"))
+	s.Add(TestResource{}, PagedHandler{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		res, err := http.Get(ts.URL + "/paged" + request.Query)
+		if err != nil {
+			t.Fatalf("%s: expected no error from request, got %s", request.Query, err.Error())
+		}
+
+		if link := res.Header.Get("Link"); link != request.Link {
+			t.Errorf("%s: expected Link '%s', got '%s'", request.Query, request.Link, link)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Query, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Query, request.Body, body)
+		}
+	}
+}
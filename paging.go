@@ -0,0 +1,256 @@
+package reason
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PagedLister implementers can list a page of a resource, returning the items
+// from offset up to limit along with the total number of items. Clients select
+// a page with a Range header such as "Range: items=0-24", which is answered
+// with http.StatusPartialContent and a Content-Range header, or with offset
+// and limit query parameters, which are answered with Link headers pointing to
+// the next and previous pages.
+type PagedLister interface {
+	Lister
+	ListResourcePage(offset int, limit int) ([]interface{}, int, error)
+}
+
+// CursorLister implementers can list a page of a resource starting at an
+// opaque cursor, returning up to limit items along with the cursor of the
+// next page, or an empty cursor on the last page. Clients select a page with
+// cursor and limit query parameters, the first page is listed when cursor is
+// empty, and the next page is pointed to by a Link header.
+type CursorLister interface {
+	Lister
+	ListResourceCursor(cursor string, limit int) ([]interface{}, string, error)
+}
+
+// defaultPageLimit is the number of items in a page when a client pages with
+// an offset or cursor but no limit.
+const defaultPageLimit = 25
+
+// SetMaxPageLimit limits the number of items a client can ask for in a page of
+// any resource, unless the resource is added with WithMaxPageLimit. Larger
+// pages are clamped to n items, or rejected with http.StatusBadRequest with
+// SetStrictPageLimit. A limit of 0, the default, disables the check.
+func (s *Server) SetMaxPageLimit(n int) {
+	s.maxPageLimit = n
+}
+
+// SetStrictPageLimit rejects pages larger than the maximum page limit instead
+// of clamping them.
+func (s *Server) SetStrictPageLimit(strict bool) {
+	s.strictPageLimit = strict
+}
+
+// WithMaxPageLimit limits the number of items a client can ask for in a page
+// of the resource, overriding the limit set with SetMaxPageLimit, such as to
+// let small reference tables be fetched whole.
+func WithMaxPageLimit(n int) ResourceOption {
+	return func(reg *registration) {
+		reg.maxPageLimit = n
+	}
+}
+
+// pageLimit returns the maximum page limit of the resource of reg, or 0 when
+// pages are not limited.
+func (s *Server) pageLimit(reg *registration) int {
+	if reg.maxPageLimit > 0 {
+		return reg.maxPageLimit
+	}
+	return s.maxPageLimit
+}
+
+// checkPageLimit returns limit clamped to the maximum page limit of the
+// resource of reg, or an error when limits are strict. The field names the
+// parameter the limit was given in, if any.
+func (s *Server) checkPageLimit(reg *registration, limit int, field string) (int, error) {
+	max := s.pageLimit(reg)
+	if max == 0 || limit <= max {
+		return limit, nil
+	}
+	if s.strictPageLimit {
+		return 0, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    "invalid_page",
+			Message: fmt.Sprintf("Pages are limited to %d items", max),
+			Field:   field,
+		}
+	}
+	return max, nil
+}
+
+// defaultLimit returns the page limit used when the client gives none.
+func (s *Server) defaultLimit(reg *registration) int {
+	if max := s.pageLimit(reg); max > 0 && max < defaultPageLimit {
+		return max
+	}
+	return defaultPageLimit
+}
+
+// listRange lists the items selected by a Range header or by offset and limit
+// query parameters. Requests without either are listed in full.
+func (s *Server) listRange(w http.ResponseWriter, r *http.Request, reg *registration, paged PagedLister) ([]interface{}, int, error) {
+	w.Header().Set("Accept-Ranges", "items")
+
+	query := r.URL.Query()
+	if query.Get(s.param("offset")) != "" || query.Get(s.param("limit")) != "" {
+		return s.listPage(w, r, reg, query, paged)
+	}
+
+	header := r.Header.Get("Range")
+	if !strings.HasPrefix(header, "items=") {
+		list, err := paged.ListResource()
+		return list, http.StatusOK, err
+	}
+
+	offset, limit, ok := parseItemRange(header[len("items="):])
+	if !ok {
+		return nil, 0, rangeError(header)
+	}
+	limit, err := s.checkPageLimit(reg, limit, "")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	list, total, err := paged.ListResourcePage(offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(list) == 0 {
+		if offset == 0 {
+			return list, http.StatusOK, nil
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("items */%d", total))
+		return nil, 0, rangeError(header)
+	}
+
+	w.Header().Set("Content-Range", fmt.Sprintf("items %d-%d/%d", offset, offset+len(list)-1, total))
+	return list, http.StatusPartialContent, nil
+}
+
+// listPage lists the page selected by offset and limit query parameters and
+// sets a Link header with the next and previous pages.
+func (s *Server) listPage(w http.ResponseWriter, r *http.Request, reg *registration, query url.Values, paged PagedLister) ([]interface{}, int, error) {
+	offset, limit := 0, s.defaultLimit(reg)
+	if v := query.Get(s.param("offset")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, 0, pageError(s.param("offset"), v)
+		}
+		offset = n
+	}
+	if v := query.Get(s.param("limit")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, 0, pageError(s.param("limit"), v)
+		}
+		if limit, err = s.checkPageLimit(reg, n, s.param("limit")); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	list, total, err := paged.ListResourcePage(offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var links []string
+	if offset+limit < total {
+		links = append(links, s.pageLink(r, query, offset+limit, limit, "next"))
+	}
+	if offset > 0 {
+		prev := offset - limit
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, s.pageLink(r, query, prev, limit, "prev"))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+	return list, http.StatusOK, nil
+}
+
+// listCursor lists the page selected by cursor and limit query parameters and
+// sets a Link header with the next page.
+func (s *Server) listCursor(w http.ResponseWriter, r *http.Request, reg *registration, lister CursorLister) ([]interface{}, error) {
+	query := r.URL.Query()
+	limit := s.defaultLimit(reg)
+	if v := query.Get(s.param("limit")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, pageError(s.param("limit"), v)
+		}
+		if limit, err = s.checkPageLimit(reg, n, s.param("limit")); err != nil {
+			return nil, err
+		}
+	}
+
+	list, next, err := lister.ListResourceCursor(query.Get(s.param("cursor")), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if next != "" {
+		page := make(url.Values, len(query))
+		for k, v := range query {
+			page[k] = v
+		}
+		page.Set(s.param("cursor"), next)
+		page.Set(s.param("limit"), strconv.Itoa(limit))
+		w.Header().Set("Link", fmt.Sprintf("<%s?%s>; rel=%q", r.URL.Path, page.Encode(), "next"))
+	}
+	return list, nil
+}
+
+// pageLink formats a Link header entry for the page at offset, keeping the
+// other query parameters of the request.
+func (s *Server) pageLink(r *http.Request, query url.Values, offset int, limit int, rel string) string {
+	page := make(url.Values, len(query))
+	for k, v := range query {
+		page[k] = v
+	}
+	page.Set(s.param("offset"), strconv.Itoa(offset))
+	page.Set(s.param("limit"), strconv.Itoa(limit))
+	return fmt.Sprintf("<%s?%s>; rel=%q", r.URL.Path, page.Encode(), rel)
+}
+
+func pageError(field string, value string) error {
+	return &APIError{
+		Status:  http.StatusBadRequest,
+		Code:    "invalid_page",
+		Message: fmt.Sprintf("Invalid %s %q", field, value),
+		Field:   field,
+	}
+}
+
+// parseItemRange parses a range of the form "first-last", where both positions
+// are inclusive.
+func parseItemRange(spec string) (int, int, bool) {
+	idx := strings.Index(spec, "-")
+	if idx == -1 {
+		return 0, 0, false
+	}
+	first, err := strconv.Atoi(strings.TrimSpace(spec[0:idx]))
+	if err != nil || first < 0 {
+		return 0, 0, false
+	}
+	last, err := strconv.Atoi(strings.TrimSpace(spec[idx+1:]))
+	if err != nil || last < first {
+		return 0, 0, false
+	}
+	return first, last - first + 1, true
+}
+
+func rangeError(header string) error {
+	return &APIError{
+		Status:  http.StatusRequestedRangeNotSatisfiable,
+		Code:    "invalid_range",
+		Message: fmt.Sprintf("Range %q cannot be satisfied", header),
+	}
+}
@@ -0,0 +1,243 @@
+package reason
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+type PagedHandler struct {
+	TestResourceHandler
+}
+
+func (ph PagedHandler) Path() string {
+	return "paged"
+}
+
+func (ph PagedHandler) ListResourcePage(offset int, limit int) ([]interface{}, int, error) {
+	list, _ := ph.ListResource()
+	if offset > len(list) {
+		offset = len(list)
+	}
+	if end := offset + limit; end < len(list) {
+		return list[offset:end], len(list), nil
+	}
+	return list[offset:], len(list), nil
+}
+
+func TestRangeLister(t *testing.T) {
+	var requests = []struct {
+		Path         string
+		Range        string
+		StatusCode   int
+		ContentRange string
+		Body         string
+	}{
+		{"/paged", "", 200, "", `[{"id":1,"name":"The Test"},{"id":2,"name":"The Other"}]`},
+		{"/paged", "items=0-0", 206, "items 0-0/2", `[{"id":1,"name":"The Test"}]`},
+		{"/paged", "items=1-24", 206, "items 1-1/2", `[{"id":2,"name":"The Other"}]`},
+		{"/paged", "items=0-24", 206, "items 0-1/2", `[{"id":1,"name":"The Test"},{"id":2,"name":"The Other"}]`},
+		{"/paged", "items=5-9", 416, "items */2", `{"error":{"status":416,"code":"invalid_range","message":"Range \"items=5-9\" cannot be satisfied"}}`},
+		{"/paged", "items=3-1", 416, "", `{"error":{"status":416,"code":"invalid_range","message":"Range \"items=3-1\" cannot be satisfied"}}`},
+		{"/paged", "items=a-b", 416, "", `{"error":{"status":416,"code":"invalid_range","message":"Range \"items=a-b\" cannot be satisfied"}}`},
+		{"/paged", "bytes=0-10", 200, "", `[{"id":1,"name":"The Test"},{"id":2,"name":"The Other"}]`},
+		{"/test", "items=0-0", 200, "", `[{"id":1,"name":"The Test"},{"id":2,"name":"The Other"}]`},
+	}
+
+	s := New()
+	s.Add(TestResource{}, PagedHandler{})
+	s.Add(TestResource{}, TestResourceHandler{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		req, err := http.NewRequest("GET", ts.URL+request.Path, nil)
+		if err != nil {
+			t.Errorf("%s: expected no error from http.NewRequest, got %s", request.Range, err.Error())
+		}
+		if request.Range != "" {
+			req.Header.Set("Range", request.Range)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Errorf("%s: expected no error from client.Do, got %s", request.Range, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Range, request.StatusCode, res.StatusCode)
+		}
+		if contentRange := res.Header.Get("Content-Range"); contentRange != request.ContentRange {
+			t.Errorf("%s: expected Content-Range '%s', got '%s'", request.Range, request.ContentRange, contentRange)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Range, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Range, request.Body, body)
+		}
+	}
+}
+
+func TestPageLinks(t *testing.T) {
+	var requests = []struct {
+		Query      string
+		StatusCode int
+		Link       string
+		Body       string
+	}{
+		{"?limit=1", 200, `</paged?limit=1&offset=1>; rel="next"`, `[{"id":1,"name":"The Test"}]`},
+		{"?offset=1&limit=1&sort=name", 200, `</paged?limit=1&offset=0&sort=name>; rel="prev"`, `[{"id":2,"name":"The Other"}]`},
+		{"?offset=1", 200, `</paged?limit=25&offset=0>; rel="prev"`, `[{"id":2,"name":"The Other"}]`},
+		{"?offset=0&limit=25", 200, "", `[{"id":1,"name":"The Test"},{"id":2,"name":"The Other"}]`},
+		{"?offset=-1", 400, "", `{"error":{"status":400,"code":"invalid_page","message":"Invalid offset \"-1\"","field":"offset"}}`},
+		{"?limit=0", 400, "", `{"error":{"status":400,"code":"invalid_page","message":"Invalid limit \"0\"","field":"limit"}}`},
+	}
+
+	s := New()
+	s.Add(TestResource{}, PagedHandler{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		res, err := http.Get(ts.URL + "/paged" + request.Query)
+		if err != nil {
+			t.Fatalf("%s: expected no error from request, got %s", request.Query, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Query, request.StatusCode, res.StatusCode)
+		}
+		if link := res.Header.Get("Link"); link != request.Link {
+			t.Errorf("%s: expected Link '%s', got '%s'", request.Query, request.Link, link)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Query, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Query, request.Body, body)
+		}
+	}
+}
+
+func TestMaxPageLimit(t *testing.T) {
+	var requests = []struct {
+		Path       string
+		Range      string
+		Strict     bool
+		StatusCode int
+		Link       string
+		Body       string
+	}{
+		{"/paged?limit=5", "", false, 200, `</paged?limit=1&offset=1>; rel="next"`, `[{"id":1,"name":"The Test"}]`},
+		{"/paged?offset=1", "", false, 200, `</paged?limit=1&offset=0>; rel="prev"`, `[{"id":2,"name":"The Other"}]`},
+		{"/paged", "items=0-9", false, 206, "", `[{"id":1,"name":"The Test"}]`},
+		{"/v2/paged?limit=5", "", false, 200, "", `[{"id":1,"name":"The Test"},{"id":2,"name":"The Other"}]`},
+		{"/paged?limit=5", "", true, 400, "", `{"error":{"status":400,"code":"invalid_page","message":"Pages are limited to 1 items","field":"limit"}}`},
+		{"/paged", "items=0-9", true, 400, "", `{"error":{"status":400,"code":"invalid_page","message":"Pages are limited to 1 items"}}`},
+		{"/v2/paged?limit=5", "", true, 200, "", `[{"id":1,"name":"The Test"},{"id":2,"name":"The Other"}]`},
+	}
+
+	for _, request := range requests {
+		s := New()
+		s.SetMaxPageLimit(1)
+		s.SetStrictPageLimit(request.Strict)
+		s.Add(TestResource{}, PagedHandler{})
+		s.Add(TestResource{}, PagedHandler{}, WithVersion("v2"), WithMaxPageLimit(5))
+
+		req := httptest.NewRequest("GET", request.Path, nil)
+		if request.Range != "" {
+			req.Header.Set("Range", request.Range)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		if rec.Code != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Path, request.StatusCode, rec.Code)
+		}
+		if link := rec.Header().Get("Link"); link != request.Link {
+			t.Errorf("%s: expected Link '%s', got '%s'", request.Path, request.Link, link)
+		}
+		if body := rec.Body.String(); body != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Path, request.Body, body)
+		}
+	}
+}
+
+type CursorHandler struct {
+	TestResourceHandler
+}
+
+func (ch CursorHandler) Path() string {
+	return "cursor"
+}
+
+func (ch CursorHandler) ListResourceCursor(cursor string, limit int) ([]interface{}, string, error) {
+	list, _ := ch.ListResource()
+	offset := 0
+	if cursor != "" {
+		n, err := strconv.Atoi(cursor)
+		if err != nil {
+			return nil, "", &APIError{Status: http.StatusBadRequest, Code: "invalid_cursor", Field: "cursor"}
+		}
+		offset = n
+	}
+	if end := offset + limit; end < len(list) {
+		return list[offset:end], strconv.Itoa(end), nil
+	}
+	return list[offset:], "", nil
+}
+
+func TestCursorLister(t *testing.T) {
+	var requests = []struct {
+		Query      string
+		StatusCode int
+		Link       string
+		Body       string
+	}{
+		{"", 200, "", `[{"id":1,"name":"The Test"},{"id":2,"name":"The Other"}]`},
+		{"?limit=1", 200, `</cursor?cursor=1&limit=1>; rel="next"`, `[{"id":1,"name":"The Test"}]`},
+		{"?cursor=1&limit=1&expand=owner", 200, "", `[{"id":2,"name":"The Other"}]`},
+		{"?cursor=x", 400, "", `{"error":{"status":400,"code":"invalid_cursor","field":"cursor"}}`},
+		{"?limit=0", 400, "", `{"error":{"status":400,"code":"invalid_page","message":"Invalid limit \"0\"","field":"limit"}}`},
+	}
+
+	s := New()
+	s.Add(TestResource{}, CursorHandler{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		res, err := http.Get(ts.URL + "/cursor" + request.Query)
+		if err != nil {
+			t.Fatalf("%s: expected no error from request, got %s", request.Query, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Query, request.StatusCode, res.StatusCode)
+		}
+		if link := res.Header.Get("Link"); link != request.Link {
+			t.Errorf("%s: expected Link '%s', got '%s'", request.Query, request.Link, link)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Query, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Query, request.Body, body)
+		}
+	}
+}
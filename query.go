@@ -0,0 +1,90 @@
+package reason
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// QueryLister implementers can list the resources matching a ListQuery. Only
+// schema fields tagged `reason:"filterable"` can be filtered on and only
+// fields tagged `reason:"sortable"` can be sorted by, requests naming other
+// fields are rejected with http.StatusBadRequest:
+//
+//	type User struct {
+//		ID    int64  `json:"id" reason:"sortable"`
+//		Email string `json:"email" reason:"filterable,sortable"`
+//	}
+type QueryLister interface {
+	Lister
+	ListResourceQuery(query ListQuery) ([]interface{}, error)
+}
+
+// ListQuery holds the filters and sort order of a list request. A request
+// such as "?email=jo@example.com&sort=-id,email" filters on the email field
+// and sorts by id descending, then email.
+type ListQuery struct {
+	Filters map[string]string
+	Sort    []SortField
+}
+
+// SortField is a field a list is sorted by.
+type SortField struct {
+	Name string
+	Desc bool
+}
+
+// parseListQuery parses the filters and sort order of a list request.
+func (s *Server) parseListQuery(r *http.Request, reg *registration) (ListQuery, error) {
+	query := ListQuery{Filters: make(map[string]string)}
+
+	fields := make(map[string]formField)
+	if t := reflect.TypeOf(reg.schema); t != nil && t.Kind() == reflect.Struct {
+		schemaFields, err := s.getSchemaFields(schemaKey{typ: t, tag: s.tagName})
+		if err != nil {
+			return query, err
+		}
+		for _, field := range schemaFields {
+			fields[field.name] = field
+		}
+	}
+
+	values := r.URL.Query()
+	for name, vals := range values {
+		field, ok := fields[name]
+		if !ok || s.isReservedParam(name) {
+			continue
+		}
+		if !field.filterable {
+			return query, queryError(name, fmt.Sprintf("Cannot filter on %q", name))
+		}
+		query.Filters[name] = vals[0]
+	}
+
+	if sort := values.Get(s.param("sort")); sort != "" {
+		for _, name := range strings.Split(sort, ",") {
+			sf := SortField{Name: strings.TrimSpace(name)}
+			if strings.HasPrefix(sf.Name, "-") {
+				sf.Name, sf.Desc = sf.Name[1:], true
+			}
+			if field, ok := fields[sf.Name]; !ok || !field.sortable {
+				return query, queryError(s.param("sort"), fmt.Sprintf("Cannot sort by %q", sf.Name))
+			}
+			query.Sort = append(query.Sort, sf)
+		}
+	} else {
+		query.Sort = append(query.Sort, reg.defaultSort...)
+	}
+
+	return query, nil
+}
+
+func queryError(field string, message string) error {
+	return &APIError{
+		Status:  http.StatusBadRequest,
+		Code:    "invalid_query",
+		Message: message,
+		Field:   field,
+	}
+}
@@ -0,0 +1,86 @@
+package reason
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type QueryResource struct {
+	ID     int64  `json:"id" reason:"sortable"`
+	Name   string `json:"name" reason:"filterable,sortable"`
+	Secret string `json:"secret"`
+}
+
+// ReservedQueryResource has fields named like reserved query parameters,
+// which are not filterable.
+type ReservedQueryResource struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name" reason:"filterable"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
+
+type QueryHandler struct{}
+
+func (qh QueryHandler) Path() string {
+	return "query"
+}
+
+func (qh QueryHandler) ListResource() ([]interface{}, error) {
+	return nil, nil
+}
+
+func (qh QueryHandler) ListResourceQuery(query ListQuery) ([]interface{}, error) {
+	return []interface{}{fmt.Sprintf("%v %v", query.Filters, query.Sort)}, nil
+}
+
+func TestQueryLister(t *testing.T) {
+	var requests = []struct {
+		Path       string
+		Query      string
+		StatusCode int
+		Body       string
+	}{
+		{"/query", "", 200, `["map[] []"]`},
+		{"/query", "?name=Jo&other=1", 200, `["map[name:Jo] []"]`},
+		{"/query", "?sort=-id,name", 200, `["map[] [{id true} {name false}]"]`},
+		{"/query", "?secret=x", 400, `{"error":{"status":400,"code":"invalid_query","message":"Cannot filter on \"secret\"","field":"secret"}}`},
+		{"/query", "?id=1", 400, `{"error":{"status":400,"code":"invalid_query","message":"Cannot filter on \"id\"","field":"id"}}`},
+		{"/query", "?sort=secret", 400, `{"error":{"status":400,"code":"invalid_query","message":"Cannot sort by \"secret\"","field":"sort"}}`},
+		{"/query", "?sort=unknown", 400, `{"error":{"status":400,"code":"invalid_query","message":"Cannot sort by \"unknown\"","field":"sort"}}`},
+		{"/sorted/query", "", 200, `["map[] [{id true} {name false}]"]`},
+		{"/sorted/query", "?sort=name", 200, `["map[] [{name false}]"]`},
+		{"/reserved/query", "?limit=5&offset=0&name=Jo", 200, `["map[name:Jo] []"]`},
+	}
+
+	s := New()
+	s.Add(QueryResource{}, QueryHandler{})
+	s.Add(QueryResource{}, QueryHandler{}, WithVersion("sorted"), WithDefaultSort("id", true), WithDefaultSort("name", false))
+	s.Add(ReservedQueryResource{}, QueryHandler{}, WithVersion("reserved"))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		res, err := http.Get(ts.URL + request.Path + request.Query)
+		if err != nil {
+			t.Fatalf("%s: expected no error from request, got %s", request.Query, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Query, request.StatusCode, res.StatusCode)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Query, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Query, request.Body, body)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package reason
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxIdleBuckets is the number of client buckets kept before buckets that
+// have refilled are discarded.
+const maxIdleBuckets = 1024
+
+// SetRateLimit limits each client to rate requests per second, allowing bursts
+// of up to burst requests. Requests over the limit are rejected with
+// http.StatusTooManyRequests and a Retry-After header. Every response carries
+// the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers of the
+// IETF draft, so that clients can slow down before they are limited. Clients
+// are identified by their remote address unless SetRateLimitKey is used. A
+// rate of 0 disables the limit.
+func (s *Server) SetRateLimit(rate float64, burst int) {
+	if rate <= 0 {
+		s.limiter = nil
+		return
+	}
+	s.limiter = &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// SetRateLimitKey sets the function identifying the client a request is
+// counted against, such as an API key header.
+func (s *Server) SetRateLimitKey(fn func(r *http.Request) string) {
+	s.rateLimitKey = fn
+}
+
+// limitRate reports whether a request is allowed by the rate limit, writing the
+// response when it is not.
+func (s *Server) limitRate(w http.ResponseWriter, r *http.Request) bool {
+	if s.limiter == nil {
+		return true
+	}
+
+	var key string
+	if s.rateLimitKey != nil {
+		key = s.rateLimitKey(r)
+	} else if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		key = host
+	} else {
+		key = r.RemoteAddr
+	}
+
+	state := s.limiter.take(key, time.Now())
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(int(s.limiter.burst)))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(state.remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(state.reset.Seconds()))))
+	if !state.allowed {
+		seconds := int(math.Ceil(state.wait.Seconds()))
+		if seconds < 1 {
+			seconds = 1
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		s.writeError(w, r, statusError(http.StatusTooManyRequests))
+	}
+	return state.allowed
+}
+
+// rateLimiter is a token bucket rate limiter keeping a bucket per client.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	lock    sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// limitState is the state of a client's bucket after a request is counted.
+type limitState struct {
+	allowed   bool
+	remaining int
+	wait      time.Duration // until a token is available, when not allowed
+	reset     time.Duration // until the bucket is full
+}
+
+// take removes a token from the client's bucket. When the bucket is empty the
+// request is not allowed and the state holds the time until a token is
+// available.
+func (l *rateLimiter) take(key string, now time.Time) limitState {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxIdleBuckets {
+			l.purge(now)
+		}
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	state := limitState{allowed: b.tokens >= 1}
+	if state.allowed {
+		b.tokens--
+	} else {
+		state.wait = l.duration(1 - b.tokens)
+	}
+	state.remaining = int(b.tokens)
+	state.reset = l.duration(l.burst - b.tokens)
+	return state
+}
+
+// duration returns the time it takes to refill tokens.
+func (l *rateLimiter) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+// purge discards the buckets of clients that would have refilled completely.
+func (l *rateLimiter) purge(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
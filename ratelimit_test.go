@@ -0,0 +1,87 @@
+package reason
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	var requests = []struct {
+		Key        string
+		StatusCode int
+		RetryAfter string
+		Remaining  string
+		Reset      string
+		Body       string
+	}{
+		{"a", 200, "", "1", "4", `{"id":1,"name":"The Test"}`},
+		{"a", 200, "", "0", "8", `{"id":1,"name":"The Test"}`},
+		{"a", 429, "4", "0", "8", `{"error":{"status":429,"code":"too_many_requests","message":"Too many requests"}}`},
+		{"b", 200, "", "1", "4", `{"id":1,"name":"The Test"}`},
+		{"a", 429, "4", "0", "8", `{"error":{"status":429,"code":"too_many_requests","message":"Too many requests"}}`},
+	}
+
+	s := New()
+	s.Add(TestResource{}, TestResourceHandler{})
+	s.SetRateLimit(0.25, 2)
+	s.SetRateLimitKey(func(r *http.Request) string {
+		return r.Header.Get("X-Client")
+	})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for k, request := range requests {
+		req, err := http.NewRequest("GET", ts.URL+"/test/1", nil)
+		if err != nil {
+			t.Errorf("%d: expected no error from http.NewRequest, got %s", k, err.Error())
+		}
+		req.Header.Set("X-Client", request.Key)
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Errorf("%d: expected no error from client.Do, got %s", k, err.Error())
+		}
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%d: expected no error from read, got %s", k, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%d: expected status code %d, got %d", k, request.StatusCode, res.StatusCode)
+		}
+		if string(body) != request.Body {
+			t.Errorf("%d: expected body '%s', got '%s'", k, request.Body, body)
+		}
+		if retryAfter := res.Header.Get("Retry-After"); retryAfter != request.RetryAfter {
+			t.Errorf("%d: expected Retry-After '%s', got '%s'", k, request.RetryAfter, retryAfter)
+		}
+		if limit := res.Header.Get("RateLimit-Limit"); limit != "2" {
+			t.Errorf("%d: expected RateLimit-Limit '2', got '%s'", k, limit)
+		}
+		if remaining := res.Header.Get("RateLimit-Remaining"); remaining != request.Remaining {
+			t.Errorf("%d: expected RateLimit-Remaining '%s', got '%s'", k, request.Remaining, remaining)
+		}
+		if reset := res.Header.Get("RateLimit-Reset"); reset != request.Reset {
+			t.Errorf("%d: expected RateLimit-Reset '%s', got '%s'", k, request.Reset, reset)
+		}
+	}
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	l := &rateLimiter{rate: 2, burst: 1, buckets: make(map[string]*bucket)}
+	now := time.Now()
+
+	if state := l.take("a", now); !state.allowed || state.remaining != 0 || state.reset != 500*time.Millisecond {
+		t.Errorf("expected first request to be allowed with a reset of 500ms, got %+v", state)
+	}
+	if state := l.take("a", now.Add(100*time.Millisecond)); state.allowed || state.wait != 400*time.Millisecond {
+		t.Errorf("expected second request to wait 400ms, got %t %s", state.allowed, state.wait)
+	}
+	if state := l.take("a", now.Add(500*time.Millisecond)); !state.allowed {
+		t.Errorf("expected request to be allowed after refill")
+	}
+}
@@ -0,0 +1,37 @@
+package reason
+
+import "net/http"
+
+// SetReadOnly rejects requests with methods other than GET, HEAD and OPTIONS
+// with http.StatusMethodNotAllowed, whatever their resource handlers
+// implement, such as for a server backed by a read replica. Paths allowed with
+// SetReadOnlyAllow are exempt.
+func (s *Server) SetReadOnly(readOnly bool) {
+	s.readOnly = readOnly
+}
+
+// SetReadOnlyAllow sets the paths that accept every method while the server is
+// read only. A path also allows the paths below it.
+func (s *Server) SetReadOnlyAllow(paths ...string) {
+	s.readOnlyAllow = allowList(paths)
+}
+
+// checkReadOnly reports whether the request method is accepted, writing the
+// response and returning false when the server is read only.
+func (s *Server) checkReadOnly(w http.ResponseWriter, r *http.Request) bool {
+	if !s.readOnly || pathAllowed(s.readOnlyAllow, r.URL.Path) {
+		return true
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	w.Header().Set("Allow", "GET, HEAD, OPTIONS")
+	s.writeError(w, r, &APIError{
+		Status:  http.StatusMethodNotAllowed,
+		Code:    "read_only",
+		Message: "The service is read only",
+	})
+	return false
+}
@@ -0,0 +1,67 @@
+package reason
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestReadOnly(t *testing.T) {
+	var requests = []struct {
+		Method     string
+		Path       string
+		StatusCode int
+		Body       string
+	}{
+		{"GET", "/test/1", 200, `{"id":1,"name":"The Test"}`},
+		{"POST", "/test", 405, `{"error":{"status":405,"code":"read_only","message":"The service is read only"}}`},
+		{"DELETE", "/test/1", 405, `{"error":{"status":405,"code":"read_only","message":"The service is read only"}}`},
+		{"POST", "/sessions", 201, `{"id":1,"name":"New"}`},
+	}
+
+	s := New()
+	s.SetReadOnly(true)
+	s.SetReadOnlyAllow("/sessions")
+	s.Add(TestResource{}, TestResourceHandler{})
+	s.Add(TestResource{}, NewMapStore("sessions"))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		var res *http.Response
+		var err error
+		switch request.Method {
+		case "POST":
+			res, err = http.PostForm(ts.URL+request.Path, url.Values{"name": {"New"}})
+		default:
+			var req *http.Request
+			req, err = http.NewRequest(request.Method, ts.URL+request.Path, nil)
+			if err != nil {
+				t.Fatalf("%s %s: expected no error from http.NewRequest, got %s", request.Method, request.Path, err.Error())
+			}
+			res, err = http.DefaultClient.Do(req)
+		}
+		if err != nil {
+			t.Fatalf("%s %s: expected no error from request, got %s", request.Method, request.Path, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s %s: expected status code %d, got %d", request.Method, request.Path, request.StatusCode, res.StatusCode)
+		}
+		if res.StatusCode == 405 && res.Header.Get("Allow") != "GET, HEAD, OPTIONS" {
+			t.Errorf("%s %s: expected Allow 'GET, HEAD, OPTIONS', got '%s'", request.Method, request.Path, res.Header.Get("Allow"))
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s %s: expected no error from read, got %s", request.Method, request.Path, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s %s: expected body '%s', got '%s'", request.Method, request.Path, request.Body, body)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package reason
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// registration holds the schema and options a resource was added with.
+type registration struct {
+	handler ResourceHandler
+	path    string
+	route   string
+	schema  interface{}
+	version string
+	key     string
+	param   string
+
+	output    reflect.Type
+	idPattern *regexp.Regexp
+	formats   []string
+
+	public bool
+
+	listCacheControl string
+	defaultSort      []SortField
+	timeFormat       string
+	transaction      bool
+	maxPageLimit     int
+	audit            bool
+	strictReplace    bool
+	required         map[string]bool
+	defaults         map[string]string
+	bulkParsers      map[string]func(io.Reader) ([]interface{}, error)
+
+	deprecated bool
+	sunset     time.Time
+}
+
+// ResourceOption configures how a resource is added to the server.
+type ResourceOption func(*registration)
+
+// WithVersion mounts the resource under a version prefix, so a handler with
+// the path "users" added with WithVersion("v2") is served at /v2/users. This
+// allows the same handler to back several versions of an API, each with its
+// own schema.
+func WithVersion(version string) ResourceOption {
+	return func(reg *registration) {
+		reg.version = strings.Trim(version, "/")
+	}
+}
+
+// WithOutputSchema sets the schema resources are written to the client with,
+// when it differs from the schema requests are parsed into. Resources of the
+// input schema returned by the handler are converted to the output schema by
+// their JSON fields, so that a password accepted on create is never written
+// back, and fields such as server timestamps can be returned without being
+// accepted. Values of any other type are written as they are, such as a
+// creation result holding a one-time secret.
+func WithOutputSchema(schema interface{}) ResourceOption {
+	return func(reg *registration) {
+		reg.output = reflect.TypeOf(schema)
+	}
+}
+
+// isSchema reports whether res is of the input or output schema of the
+// resource, or a pointer to one.
+func (reg *registration) isSchema(res interface{}) bool {
+	t := indirectType(res)
+	return t != nil && (t == reflect.TypeOf(reg.schema) || t == reg.output)
+}
+
+// indirectType returns the type of v, or the type it points to when v is a
+// pointer.
+func indirectType(v interface{}) reflect.Type {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+// convertSchema copies the JSON fields of res into a new value of type t.
+func convertSchema(res interface{}, t reflect.Type) (interface{}, error) {
+	if reflect.TypeOf(res) == t {
+		return res, nil
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	val := reflect.New(t)
+	if err := json.Unmarshal(data, val.Interface()); err != nil {
+		return nil, err
+	}
+	return val.Elem().Interface(), nil
+}
+
+// WithListCacheControl sets the Cache-Control header of list responses for
+// the resource, such as "private, max-age=60". Lists are always sent with an
+// ETag, so "no-cache" has clients revalidate them with If-None-Match.
+func WithListCacheControl(directives string) ResourceOption {
+	return func(reg *registration) {
+		reg.listCacheControl = directives
+	}
+}
+
+// WithDefaultSort sets the order lists of the resource are sorted in when the
+// client does not ask for one with the sort query parameter, such as newest
+// first with WithDefaultSort("created", true). It is passed to a QueryLister
+// in ListQuery.Sort. Adding the option again sorts by further fields.
+func WithDefaultSort(field string, desc bool) ResourceOption {
+	return func(reg *registration) {
+		reg.defaultSort = append(reg.defaultSort, SortField{Name: field, Desc: desc})
+	}
+}
+
+// Common patterns for WithIDPattern.
+const (
+	PatternInt  = `[0-9]+`
+	PatternUUID = `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`
+)
+
+// WithIDPattern constrains the id path segment of the resource's routes to
+// values matching the regular expression pattern in full, such as PatternInt
+// or PatternUUID. Requests with other ids are answered as if the route did not
+// exist. WithIDPattern panics if pattern does not compile.
+func WithIDPattern(pattern string) ResourceOption {
+	re := regexp.MustCompile(`^(?:` + pattern + `)$`)
+	return func(reg *registration) {
+		reg.idPattern = re
+	}
+}
+
+// WithDeprecation marks the resource as deprecated, setting a
+// "Deprecation: true" header on every response for its routes. A non-zero
+// sunset is sent in a Sunset header as the time the resource will be removed.
+func WithDeprecation(sunset time.Time) ResourceOption {
+	return func(reg *registration) {
+		reg.deprecated = true
+		reg.sunset = sunset
+	}
+}
+
+// setDeprecationHeaders sets the headers for a deprecated resource.
+func (reg *registration) setDeprecationHeaders(w http.ResponseWriter) {
+	if !reg.deprecated {
+		return
+	}
+	w.Header().Set("Deprecation", "true")
+	if !reg.sunset.IsZero() {
+		w.Header().Set("Sunset", reg.sunset.UTC().Format(http.TimeFormat))
+	}
+}
@@ -0,0 +1,387 @@
+package reason
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type TestResourceV2 struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Title string `json:"title"`
+}
+
+type VersionedHandler struct {
+	TestResourceHandler
+}
+
+func (vh VersionedHandler) CreateResource(resource interface{}) (interface{}, error) {
+	if tr, ok := resource.(TestResourceV2); ok {
+		tr.ID = 3
+		return tr, nil
+	}
+	return vh.TestResourceHandler.CreateResource(resource)
+}
+
+func TestWithVersion(t *testing.T) {
+	form := url.Values{}
+	form.Add("name", "New Test")
+	form.Add("title", "Dr")
+
+	var requests = []struct {
+		Path       string
+		StatusCode int
+		Body       string
+		Data       url.Values
+	}{
+		{"/v1/test/1", 200, `{"id":1,"name":"The Test"}`, nil},
+		{"/v2/test/1", 200, `{"id":1,"name":"The Test"}`, nil},
+		{"/v1/test", 201, `{"id":3,"name":"New Test"}`, form},
+		{"/v2/test", 201, `{"id":3,"name":"New Test","title":"Dr"}`, form},
+		{"/test/1", 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`, nil},
+		{"/v3/test/1", 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`, nil},
+	}
+
+	s := New()
+	s.Add(TestResource{}, VersionedHandler{}, WithVersion("v1"))
+	s.Add(TestResourceV2{}, VersionedHandler{}, WithVersion("/v2/"))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		var res *http.Response
+		var err error
+		if request.Data != nil {
+			res, err = http.PostForm(ts.URL+request.Path, request.Data)
+		} else {
+			res, err = http.Get(ts.URL + request.Path)
+		}
+		if err != nil {
+			t.Errorf("%s: expected no error from request, got %s", request.Path, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Path, request.StatusCode, res.StatusCode)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Path, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Path, request.Body, body)
+		}
+	}
+}
+
+func TestWithDeprecation(t *testing.T) {
+	var requests = []struct {
+		Method      string
+		Path        string
+		StatusCode  int
+		Deprecation string
+		Sunset      string
+	}{
+		{"GET", "/v1/test/1", 200, "true", "Fri, 01 Jan 2100 00:00:00 GMT"},
+		{"GET", "/v1/test/9", 404, "true", "Fri, 01 Jan 2100 00:00:00 GMT"},
+		{"GET", "/v1/test", 200, "true", "Fri, 01 Jan 2100 00:00:00 GMT"},
+		{"DELETE", "/v1/test/1", 200, "true", "Fri, 01 Jan 2100 00:00:00 GMT"},
+		{"GET", "/v2/test/1", 200, "true", ""},
+		{"GET", "/v3/test/1", 200, "", ""},
+	}
+
+	s := New()
+	s.Add(TestResource{}, TestResourceHandler{}, WithVersion("v1"), WithDeprecation(time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)))
+	s.Add(TestResource{}, TestResourceHandler{}, WithVersion("v2"), WithDeprecation(time.Time{}))
+	s.Add(TestResource{}, TestResourceHandler{}, WithVersion("v3"))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		req, err := http.NewRequest(request.Method, ts.URL+request.Path, nil)
+		if err != nil {
+			t.Fatalf("%s %s: expected no error from http.NewRequest, got %s", request.Method, request.Path, err.Error())
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: expected no error from request, got %s", request.Method, request.Path, err.Error())
+		}
+		res.Body.Close()
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s %s: expected status code %d, got %d", request.Method, request.Path, request.StatusCode, res.StatusCode)
+		}
+		if deprecation := res.Header.Get("Deprecation"); deprecation != request.Deprecation {
+			t.Errorf("%s %s: expected Deprecation '%s', got '%s'", request.Method, request.Path, request.Deprecation, deprecation)
+		}
+		if sunset := res.Header.Get("Sunset"); sunset != request.Sunset {
+			t.Errorf("%s %s: expected Sunset '%s', got '%s'", request.Method, request.Path, request.Sunset, sunset)
+		}
+	}
+}
+
+func TestWithIDPattern(t *testing.T) {
+	var requests = []struct {
+		Method     string
+		Path       string
+		StatusCode int
+	}{
+		{"GET", "/ints/test/1", 200},
+		{"GET", "/ints/test/abc", 404},
+		{"GET", "/ints/test/1a", 404},
+		{"DELETE", "/ints/test/abc", 404},
+		{"GET", "/ints/test", 200},
+		{"GET", "/uuids/test/3", 404},
+		{"GET", "/uuids/test/123e4567-e89b-12d3-a456-426614174000", 404},
+		{"GET", "/slugs/test/1", 200},
+		{"GET", "/slugs/test/the-test", 404},
+	}
+
+	s := New()
+	s.Add(TestResource{}, TestResourceHandler{}, WithVersion("ints"), WithIDPattern(PatternInt))
+	s.Add(TestResource{}, TestResourceHandler{}, WithVersion("uuids"), WithIDPattern(PatternUUID))
+	s.Add(TestResource{}, TestResourceHandler{}, WithVersion("slugs"), WithIDPattern(`[a-z0-9]`))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		req, err := http.NewRequest(request.Method, ts.URL+request.Path, nil)
+		if err != nil {
+			t.Fatalf("%s %s: expected no error from http.NewRequest, got %s", request.Method, request.Path, err.Error())
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: expected no error from request, got %s", request.Method, request.Path, err.Error())
+		}
+		res.Body.Close()
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s %s: expected status code %d, got %d", request.Method, request.Path, request.StatusCode, res.StatusCode)
+		}
+	}
+}
+
+func TestRemove(t *testing.T) {
+	s := New()
+	s.Add(TestResource{}, TestResourceHandler{})
+	s.Add(TestResource{}, TestResourceHandler{}, WithVersion("v2"))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	get := func(path string) int {
+		res, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("%s: expected no error from request, got %s", path, err.Error())
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	if removed := s.Remove("/v2/test/"); !removed {
+		t.Errorf("expected v2/test to be removed")
+	}
+	if removed := s.Remove("v2/test"); removed {
+		t.Errorf("expected v2/test to be removed once")
+	}
+	if status := get("/v2/test/1"); status != 404 {
+		t.Errorf("/v2/test/1: expected status code 404 after Remove, got %d", status)
+	}
+	if status := get("/test/1"); status != 200 {
+		t.Errorf("/test/1: expected status code 200, got %d", status)
+	}
+
+	// Resources can be added again while requests are served
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			get("/test/1")
+		}
+	}()
+	s.Add(TestResource{}, TestResourceHandler{}, WithVersion("v2"))
+	<-done
+
+	if status := get("/v2/test/1"); status != 200 {
+		t.Errorf("/v2/test/1: expected status code 200 after Add, got %d", status)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected Add of a conflicting route to panic")
+			}
+		}()
+		s.Add(TestResource{}, TestResourceHandler{})
+	}()
+	if status := get("/test/1"); status != 200 {
+		t.Errorf("/test/1: expected status code 200 after a failed Add, got %d", status)
+	}
+}
+
+type SignupInput struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+type SignupOutput struct {
+	ID      int64  `json:"id"`
+	Name    string `json:"name"`
+	Created string `json:"created"`
+}
+
+type SignupHandler struct {
+	*MapStore
+}
+
+func (sh SignupHandler) CreateResource(resource interface{}) (interface{}, error) {
+	res, err := sh.MapStore.CreateResource(resource)
+	if err != nil {
+		return nil, err
+	}
+	input := res.(SignupInput)
+	return SignupOutput{ID: input.ID, Name: input.Name, Created: "2020-01-02"}, nil
+}
+
+// PointerSignupHandler returns a pointer to the input schema from creates.
+type PointerSignupHandler struct {
+	*MapStore
+}
+
+func (ph PointerSignupHandler) CreateResource(resource interface{}) (interface{}, error) {
+	res, err := ph.MapStore.CreateResource(resource)
+	if err != nil {
+		return nil, err
+	}
+	input := res.(SignupInput)
+	return &input, nil
+}
+
+func TestWithOutputSchema(t *testing.T) {
+	form := url.Values{}
+	form.Add("name", "Jo")
+	form.Add("password", "hunter2")
+	form.Add("created", "1999-01-01")
+
+	var requests = []struct {
+		Method     string
+		Path       string
+		StatusCode int
+		Body       string
+	}{
+		{"POST", "/signups", 201, `{"id":1,"name":"Jo","created":"2020-01-02"}`},
+		{"GET", "/signups/1", 200, `{"id":1,"name":"Jo","created":""}`},
+		{"GET", "/signups", 200, `[{"id":1,"name":"Jo","created":""}]`},
+		{"POST", "/pointers", 201, `{"id":1,"name":"Jo","created":""}`},
+	}
+
+	s := New()
+	s.Add(SignupInput{}, SignupHandler{NewMapStore("signups")}, WithOutputSchema(SignupOutput{}))
+	s.Add(SignupInput{}, PointerSignupHandler{NewMapStore("pointers")}, WithOutputSchema(SignupOutput{}))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		var res *http.Response
+		var err error
+		if request.Method == "POST" {
+			res, err = http.PostForm(ts.URL+request.Path, form)
+		} else {
+			res, err = http.Get(ts.URL + request.Path)
+		}
+		if err != nil {
+			t.Fatalf("%s %s: expected no error from request, got %s", request.Method, request.Path, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s %s: expected status code %d, got %d", request.Method, request.Path, request.StatusCode, res.StatusCode)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s %s: expected no error from read, got %s", request.Method, request.Path, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s %s: expected body '%s', got '%s'", request.Method, request.Path, request.Body, body)
+		}
+	}
+}
+
+type TokenResult struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Secret string `json:"secret"`
+}
+
+type TokenHandler struct {
+	*MapStore
+}
+
+func (th TokenHandler) CreateResource(resource interface{}) (interface{}, error) {
+	res, err := th.MapStore.CreateResource(resource)
+	if err != nil {
+		return nil, err
+	}
+	tr := res.(TestResource)
+	return TokenResult{ID: tr.ID, Name: tr.Name, Secret: "s3cret"}, nil
+}
+
+func TestCreateResult(t *testing.T) {
+	form := url.Values{}
+	form.Add("name", "Token")
+
+	var requests = []struct {
+		Method     string
+		Path       string
+		StatusCode int
+		Body       string
+	}{
+		{"POST", "/v1/tokens", 201, `{"id":1,"name":"Token","secret":"s3cret"}`},
+		{"GET", "/v1/tokens/1", 200, `{"id":1,"name":"Token"}`},
+		{"POST", "/v2/tokens", 201, `{"id":1,"name":"Token","secret":"s3cret"}`},
+		{"GET", "/v2/tokens/1", 200, `{"id":1}`},
+	}
+
+	s := New()
+	s.Add(TestResource{}, TokenHandler{NewMapStore("tokens")}, WithVersion("v1"))
+	s.Add(TestResource{}, TokenHandler{NewMapStore("tokens")}, WithVersion("v2"), WithOutputSchema(struct {
+		ID int64 `json:"id"`
+	}{}))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		var res *http.Response
+		var err error
+		if request.Method == "POST" {
+			res, err = http.PostForm(ts.URL+request.Path, form)
+		} else {
+			res, err = http.Get(ts.URL + request.Path)
+		}
+		if err != nil {
+			t.Fatalf("%s %s: expected no error from request, got %s", request.Method, request.Path, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s %s: expected status code %d, got %d", request.Method, request.Path, request.StatusCode, res.StatusCode)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s %s: expected no error from read, got %s", request.Method, request.Path, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s %s: expected body '%s', got '%s'", request.Method, request.Path, request.Body, body)
+		}
+	}
+}
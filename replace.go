@@ -0,0 +1,93 @@
+package reason
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// Replacer implementers will expose a PUT method to replace the resource at an
+// id with the request body. A resource that does not exist is created, with
+// http.StatusCreated and a Location header, unless the resource is added
+// WithStrictReplace. The existing resource is looked up with GetResource to
+// tell the two apart, and fields tagged `reason:"immutable"` keep its values.
+type Replacer interface {
+	Getter
+	ReplaceResource(id string, resource interface{}) (interface{}, error)
+}
+
+// WithStrictReplace answers PUT requests for ids that do not exist with
+// http.StatusNotFound instead of creating the resource, for resources that
+// can only be created by the server.
+func WithStrictReplace() ResourceOption {
+	return func(reg *registration) {
+		reg.strictReplace = true
+	}
+}
+
+func (s *Server) replaceRequest(w http.ResponseWriter, r *http.Request, reg *registration, id string, replacer Replacer, data interface{}) {
+	res, err := s.getResource(reg, replacer, id)
+	exists := err == nil
+	if err != nil && (reg.strictReplace || !errors.Is(err, ErrNotFound)) {
+		s.writeError(w, r, err)
+		return
+	}
+
+	op, status := OperationCreate, http.StatusCreated
+	if exists {
+		op, status = OperationUpdate, http.StatusOK
+		if err := s.checkPrecondition(r); err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+		if !s.checkIfMatch(w, r, reg, replacer, res) {
+			return
+		}
+		if data, err = s.keepImmutableFields(reg, data, res); err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+	} else if r.Header.Get("If-Match") != "" {
+		// If-Match never matches a resource that does not exist
+		s.writeError(w, r, statusError(http.StatusPreconditionFailed))
+		return
+	}
+
+	if data, err = s.stampTimestamps(reg, data, op); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	if data, err = s.stampActors(r, reg, data, op); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	if err := validate(r, replacer, data, op); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	if reg.cancelled(r) {
+		return
+	}
+	response, err := replacer.ReplaceResource(id, data)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	if !exists {
+		w.Header().Set("Location", reg.route+"/"+url.PathEscape(id))
+	}
+
+	rep, err := s.represent(reg, replacer, response)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	s.notify(reg, op, id, rep)
+
+	if rep, err = authorizeFields(r, reg, replacer, response, rep); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	s.writeResource(w, r, status, rep)
+}
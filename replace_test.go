@@ -0,0 +1,73 @@
+package reason
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type ReplaceHandler struct {
+	items map[string]TestResource
+}
+
+func (rh ReplaceHandler) Path() string {
+	return "replaced"
+}
+
+func (rh ReplaceHandler) GetResource(id string) (interface{}, error) {
+	res, ok := rh.items[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return res, nil
+}
+
+func (rh ReplaceHandler) ReplaceResource(id string, resource interface{}) (interface{}, error) {
+	res := resource.(TestResource)
+	res.ID, _ = strconv.ParseInt(id, 10, 64)
+	rh.items[id] = res
+	return res, nil
+}
+
+func TestReplacer(t *testing.T) {
+	var requests = []struct {
+		Path       string
+		Name       string
+		IfMatch    string
+		StatusCode int
+		Location   string
+		Body       string
+	}{
+		{"/replaced/1", "First", "", 200, "", `{"id":1,"name":"First"}`},
+		{"/replaced/2", "Second", "", 201, "/replaced/2", `{"id":2,"name":"Second"}`},
+		{"/replaced/2", "Again", "", 200, "", `{"id":2,"name":"Again"}`},
+		{"/replaced/3", "Third", `"any"`, 412, "", `{"error":{"status":412,"code":"precondition_failed","message":"Precondition failed"}}`},
+		{"/strict/replaced/1", "First", "", 200, "", `{"id":1,"name":"First"}`},
+		{"/strict/replaced/2", "Second", "", 404, "", `{"error":{"status":404,"code":"not_found","message":"Not found"}}`},
+	}
+
+	s := New()
+	s.Add(TestResource{}, ReplaceHandler{map[string]TestResource{"1": {1, "One"}}})
+	s.Add(TestResource{}, ReplaceHandler{map[string]TestResource{"1": {1, "One"}}}, WithVersion("strict"), WithStrictReplace())
+
+	for _, request := range requests {
+		req := httptest.NewRequest("PUT", request.Path, strings.NewReader("name="+request.Name))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if request.IfMatch != "" {
+			req.Header.Set("If-Match", request.IfMatch)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		if rec.Code != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Path, request.StatusCode, rec.Code)
+		}
+		if location := rec.Header().Get("Location"); location != request.Location {
+			t.Errorf("%s: expected Location '%s', got '%s'", request.Path, request.Location, location)
+		}
+		if body := rec.Body.String(); body != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Path, request.Body, body)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package reason
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// SetRequestIDHeader identifies each request by the header name, such as
+// "X-Request-ID", generating an id for requests without one. The id is stored
+// in the request context, see RequestIDFromContext, sent back in the same
+// header of the response and included in the log of unhandled errors, so
+// that failures can be correlated with traces.
+func (s *Server) SetRequestIDHeader(name string) {
+	s.requestIDHeader = name
+}
+
+// RequestIDFromContext returns the id of a request, or an empty string when
+// the server has no request id header configured.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// withRequestID stores the id of a request in its context and sets it on the
+// response.
+func (s *Server) withRequestID(w http.ResponseWriter, r *http.Request) *http.Request {
+	if s.requestIDHeader == "" {
+		return r
+	}
+
+	id := r.Header.Get(s.requestIDHeader)
+	if id == "" {
+		id = newRequestID()
+	}
+	w.Header().Set(s.requestIDHeader, id)
+	return r.WithContext(context.WithValue(r.Context(), requestIDKey, id))
+}
+
+// newRequestID generates a random request id.
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
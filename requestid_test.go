@@ -0,0 +1,51 @@
+package reason
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	var logs bytes.Buffer
+	s := New(WithLogger(log.New(&logs, "", 0)))
+	s.SetRequestIDHeader("X-Request-ID")
+	s.Add(TestResource{}, FailingHandler{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	req, err := http.NewRequest("GET", ts.URL+"/fail/1", nil)
+	if err != nil {
+		t.Fatalf("expected no error from http.NewRequest, got %s", err.Error())
+	}
+	req.Header.Set("X-Request-ID", "abc")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("expected no error from request, got %s", err.Error())
+	}
+	res.Body.Close()
+
+	if id := res.Header.Get("X-Request-ID"); id != "abc" {
+		t.Errorf("expected X-Request-ID 'abc', got '%s'", id)
+	}
+	if !strings.Contains(logs.String(), "Unhandled error: Failed to get 1 (GET /fail/1, request abc)") {
+		t.Errorf("expected request to be logged with the error, got '%s'", logs.String())
+	}
+
+	res, err = http.Get(ts.URL + "/fail/2")
+	if err != nil {
+		t.Fatalf("expected no error from request, got %s", err.Error())
+	}
+	res.Body.Close()
+
+	id := res.Header.Get("X-Request-ID")
+	if len(id) != 32 {
+		t.Errorf("expected a generated X-Request-ID, got '%s'", id)
+	}
+	if !strings.Contains(logs.String(), "(GET /fail/2, request "+id+")") {
+		t.Errorf("expected generated request id to be logged, got '%s'", logs.String())
+	}
+}
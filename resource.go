@@ -1,11 +1,41 @@
 package reason
 
-import "errors"
+import (
+	"errors"
+	"net/http"
+)
 
 // ErrNotFound should be returned when a resource cannot be found, will cause the
 // server to return http.StatusNotFound.
 var ErrNotFound = errors.New("Resource not found")
 
+// ErrConflict should be returned when a resource conflicts with an existing one,
+// such as a create that violates a uniqueness constraint, will cause the server
+// to return http.StatusConflict. Use NewConflict to name the conflicting field.
+var ErrConflict = errors.New("Resource conflict")
+
+// ErrGone should be returned when a resource existed but has been removed, such
+// as a soft-deleted resource, will cause the server to return
+// http.StatusGone. Return an APIError or an HTTPError to respond with any other
+// status.
+var ErrGone = errors.New("Resource gone")
+
+// Operation is an operation performed on a resource.
+type Operation string
+
+// Operations performed on resources, see OperationFromContext.
+const (
+	OperationGet    Operation = "get"
+	OperationList   Operation = "list"
+	OperationCreate Operation = "create"
+	OperationUpdate Operation = "update"
+	OperationDelete Operation = "delete"
+
+	// OperationReplace is the operation of a PUT to a Replacer, which is
+	// validated and notified as a create or an update
+	OperationReplace Operation = "replace"
+)
+
 // ResourceHandler does thingz
 type ResourceHandler interface {
 	Path() string
@@ -17,18 +47,26 @@ type Getter interface {
 }
 
 // Lister implementers will expose a GET method to fetch a list of that
-// resource.
+// resource. Schema fields tagged `reason:"optional"`, such as a long
+// description, are left out of lists unless the client asks for them with the
+// include query parameter, for example ?include=description.
 type Lister interface {
 	ListResource() ([]interface{}, error)
 }
 
 // Creator implementers will expose a POST method to create a new resource.
+// The value returned is written as the response and need not be of the
+// resource schema, so a create can return a creation result, such as a
+// one-time secret, that getting the resource does not. Creates returning nil,
+// such as logging an event, respond with http.StatusNoContent.
 type Creator interface {
 	CreateResource(resource interface{}) (interface{}, error)
 }
 
 // Updater implementers will expose a POST/PUT method to update a single
-// resource.
+// resource. Schema fields tagged `reason:"immutable"`, such as a creation
+// time, are not read from update bodies and keep the value of the existing
+// resource in data when it is of the resource schema.
 type Updater interface {
 	Getter
 	UpdateResource(resource interface{}, data interface{}) (interface{}, error)
@@ -39,3 +77,37 @@ type Deleter interface {
 	Getter
 	DeleteResource(resource interface{}) error
 }
+
+// ResultDeleter implementers will expose a DELETE method like Deleter, with the
+// returned DeleteResult setting the status and headers of the response.
+type ResultDeleter interface {
+	Getter
+	DeleteResourceResult(resource interface{}) (*DeleteResult, error)
+}
+
+// DeleteResult customises the response to a delete, such as
+// http.StatusAccepted for a deletion that has been queued. A zero Status
+// responds with http.StatusOK.
+type DeleteResult struct {
+	Status int
+	Header http.Header
+}
+
+// deleterFor returns handler if it implements Deleter or ResultDeleter.
+func deleterFor(handler ResourceHandler) (Getter, bool) {
+	switch deleter := handler.(type) {
+	case ResultDeleter:
+		return deleter, true
+	case Deleter:
+		return deleter, true
+	}
+	return nil, false
+}
+
+// deleteResource deletes res through the handler, preferring ResultDeleter.
+func deleteResource(deleter Getter, res interface{}) (*DeleteResult, error) {
+	if rd, ok := deleter.(ResultDeleter); ok {
+		return rd.DeleteResourceResult(res)
+	}
+	return nil, deleter.(Deleter).DeleteResource(res)
+}
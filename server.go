@@ -1,173 +1,1020 @@
 package reason
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
+	"mime"
 	"net/http"
+	"net/url"
 	"reflect"
+	"runtime/debug"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 )
 
 // Server test
 type Server struct {
-	router *httprouter.Router
+	router      atomic.Pointer[httprouter.Router]
+	basePath    string
+	tagName     string
+	paramPrefix string
+	logger      *log.Logger
+
+	bodyLogLimit int
+
+	jwtVerifier func(token string) (Claims, error)
+
+	routesLock    sync.Mutex
+	registrations []*registration
 
 	formCacheLock sync.RWMutex
-	formCache     map[reflect.Type][]formField
+	formCache     map[schemaKey][]formField
+
+	maxBodyBytes    int64
+	maxBodyBytesFor map[string]int64
+	maxURLLength    int
+
+	maxPageLimit    int
+	strictPageLimit bool
+
+	flagsHeader     string
+	requestIDHeader string
+
+	languages  []string
+	translator func(language string, err *APIError) string
+
+	stackTraces bool
+
+	lowercasePaths         bool
+	caseInsensitiveRouting bool
+
+	bodyPreprocessor      func(r *http.Request) error
+	disallowTrailingJSON  bool
+	disallowUnknownFields bool
+	requireBody           bool
+	requirePrecondition   bool
+	timestamps            bool
+	idGenerator           func() string
+	problemJSON           bool
+	errorBodies           bool
+	descriptiveNotFound   bool
+	describeOptions       bool
+	transaction           TransactionFunc
+	store                 interface{}
+	observer              func(r *http.Request, route string, status int, duration time.Duration)
+
+	webhook *webhook
+
+	limiter      *rateLimiter
+	rateLimitKey func(r *http.Request) string
+
+	zeroPolicy ZeroPolicy
+
+	concurrency map[string]chan struct{}
+	flights     *flightGroup
+
+	strictSchemas bool
+
+	encoders   map[string]encoder
+	mediaTypes []string
+	charset    string
+	envelope   bool
+	apiVersion string
+
+	compression bool
+	encodings   []contentCoding
+
+	readOnly      bool
+	readOnlyAllow []string
+
+	maintenance      atomic.Bool
+	maintenanceAllow atomic.Pointer[[]string]
+
+	drainLock sync.Mutex
+	draining  bool
+	inflight  int
+	drained   chan struct{}
 }
 
-// New creates a new instance of Server.
-func New() *Server {
+// New creates a new instance of Server configured with opts.
+func New(opts ...Option) *Server {
 	s := &Server{}
-	s.router = httprouter.New()
-	s.formCache = make(map[reflect.Type][]formField)
+	s.logger = log.Default()
+	s.router.Store(s.newRouter(nil))
+	s.formCache = make(map[schemaKey][]formField)
+	s.maxBodyBytesFor = make(map[string]int64)
+	s.concurrency = make(map[string]chan struct{})
+	s.encodings = defaultEncodings()
+	s.encoders = map[string]encoder{"application/json": encodeJSON}
+	s.mediaTypes = []string{"application/json"}
+	s.charset = "utf-8"
+	s.errorBodies = true
 
-	s.router.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusNotFound)
-	})
+	for _, opt := range opts {
+		opt(s)
+	}
 
 	return s
 }
 
-// Add a resource to be handled.
-func (s *Server) Add(resourceSchema interface{}, handler ResourceHandler) {
+// Add a resource to be handled. The resource schema is a struct, or a map
+// with string keys, such as map[string]interface{}, for schemaless documents.
+func (s *Server) Add(resourceSchema interface{}, handler ResourceHandler, opts ...ResourceOption) {
+	reg := &registration{schema: resourceSchema}
+	for _, opt := range opts {
+		opt(reg)
+	}
+
 	path := handler.Path()
+	if reg.version != "" {
+		path = reg.version + "/" + path
+	}
+	if s.lowercasePaths {
+		path = strings.ToLower(path)
+	}
+	reg.path = path
+
+	route := "/" + path
+	if s.basePath != "" {
+		route = "/" + s.basePath + route
+	}
+	if s.lowercasePaths {
+		route = strings.ToLower(route)
+	}
+	reg.route = route
+
+	param := "id"
+	if reg.key != "" {
+		param = reg.key
+	}
+	reg.param = param
+
+	if s.strictSchemas {
+		if err := s.checkSchemaFields(resourceSchema); err != nil {
+			panic(err)
+		}
+	}
+	if err := s.checkFieldRules(reg); err != nil {
+		panic(err)
+	}
+
+	reg.handler = handler
+
+	s.routesLock.Lock()
+	defer s.routesLock.Unlock()
+
+	// Build the router before keeping the registration, adding a conflicting
+	// route panics and must leave the server unchanged
+	regs := append(s.registrations[:len(s.registrations):len(s.registrations)], reg)
+	s.router.Store(s.newRouter(regs))
+	s.registrations = regs
+}
+
+// Remove stops serving the resource at path, such as "users" or "v2/users" for
+// a resource added with WithVersion("v2"), and reports whether it was found.
+// Resources can be added and removed while the server is serving requests,
+// requests already routed to a removed resource are completed.
+func (s *Server) Remove(path string) bool {
+	path = strings.Trim(path, "/")
+	if s.lowercasePaths {
+		path = strings.ToLower(path)
+	}
+
+	s.routesLock.Lock()
+	defer s.routesLock.Unlock()
+
+	regs := make([]*registration, 0, len(s.registrations))
+	for _, reg := range s.registrations {
+		if reg.path != path {
+			regs = append(regs, reg)
+		}
+	}
+	if len(regs) == len(s.registrations) {
+		return false
+	}
+
+	s.router.Store(s.newRouter(regs))
+	s.registrations = regs
+	s.forgetSchemaFields(path)
+	return true
+}
+
+// newRouter creates a router serving the routes of regs. Routers are never
+// modified once built, so they are replaced as a whole when resources are
+// added or removed.
+func (s *Server) newRouter(regs []*registration) *httprouter.Router {
+	router := httprouter.New()
+	// Paths are cleaned and folded before routing, so the router must not
+	// redirect to a differently cased path on its own.
+	router.RedirectFixedPath = false
+	router.NotFound = http.HandlerFunc(s.notFound)
+	router.MethodNotAllowed = http.HandlerFunc(s.methodNotAllowed)
+	for _, reg := range regs {
+		s.addRoutes(router, reg)
+	}
+	return router
+}
+
+func (s *Server) notFound(w http.ResponseWriter, r *http.Request) {
+	s.writeError(w, r, ErrNotFound)
+}
+
+// allowOrder is the order methods are listed in Allow headers.
+var allowOrder = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+// methodNotAllowed answers requests for a path that is routed for other
+// methods, listing them in the Allow header in a stable order. OPTIONS is
+// always allowed, as the router answers it for every routed path.
+func (s *Server) methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	router := s.router.Load()
+	var allowed []string
+	for _, method := range allowOrder {
+		if handle, _, _ := router.Lookup(method, r.URL.Path); handle != nil || method == http.MethodOptions {
+			allowed = append(allowed, method)
+		}
+	}
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	s.writeError(w, r, &APIError{
+		Status:  http.StatusMethodNotAllowed,
+		Code:    "method_not_allowed",
+		Message: "Method not allowed",
+	})
+}
+
+// addRoutes adds the routes for the operations implemented by the handler of
+// reg.
+func (s *Server) addRoutes(router *httprouter.Router, reg *registration) {
+	handler, route, param := reg.handler, reg.route, reg.param
+	item := route + "/:" + param
 
 	if getter, ok := handler.(Getter); ok {
-		s.router.GET("/"+path+"/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-			s.getRequest(w, r, ps.ByName("id"), getter)
+		fn := s.route(reg, OperationGet, item, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+			s.getRequest(w, r, reg, ps.ByName(param), bind(getter, r))
 		})
+		router.GET(item, fn)
+		router.HEAD(item, head(fn))
 	}
 	if lister, ok := handler.(Lister); ok {
-		s.router.GET("/"+path, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-			s.listRequest(w, r, lister)
+		fn := s.route(reg, OperationList, route, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+			s.listRequest(w, r, reg, bind(lister, r))
 		})
+		router.GET(route, fn)
+		router.HEAD(route, head(fn))
 	}
 	if creator, ok := handler.(Creator); ok {
-		fn := func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-			data, err := s.parseForm(r, resourceSchema)
+		fn := s.route(reg, OperationCreate, route, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+			if bulk, ok := creator.(BulkCreator); ok {
+				if parse, ok := s.bulkParser(r, reg); ok {
+					s.transact(w, r, true, func(w http.ResponseWriter, r *http.Request) {
+						s.bulkCreateRequest(w, r, reg, bind(bulk, r), parse)
+					})
+					return
+				}
+			}
+			data, err := s.parseRequest(w, r, reg)
 			if err != nil {
-				s.writeError(w, err)
-			} else {
-				s.createRequest(w, r, creator, data)
+				s.writeError(w, r, err)
+				return
 			}
-		}
-		s.router.POST("/"+path, fn)
-		s.router.PUT("/"+path, fn)
+			s.transact(w, r, reg.transaction, func(w http.ResponseWriter, r *http.Request) {
+				s.createRequest(w, r, reg, bind(creator, r), data)
+			})
+		})
+		router.POST(route, fn)
+		router.PUT(route, fn)
 	}
 	if updater, ok := handler.(Updater); ok {
-		fn := func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-			data, err := s.parseForm(r, resourceSchema)
+		fn := s.route(reg, OperationUpdate, item, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+			data, err := s.parseRequest(w, r, reg)
+			if err != nil {
+				s.writeError(w, r, err)
+				return
+			}
+			s.transact(w, r, reg.transaction, func(w http.ResponseWriter, r *http.Request) {
+				s.updateRequest(w, r, reg, ps.ByName(param), bind(updater, r), data)
+			})
+		})
+		router.POST(item, fn)
+	}
+	if replacer, ok := handler.(Replacer); ok {
+		fn := s.route(reg, OperationReplace, item, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+			data, err := s.parseRequest(w, r, reg)
 			if err != nil {
-				s.writeError(w, err)
-			} else {
-				s.updateRequest(w, r, ps.ByName("id"), updater, data)
+				s.writeError(w, r, err)
+				return
 			}
+			s.transact(w, r, reg.transaction, func(w http.ResponseWriter, r *http.Request) {
+				s.replaceRequest(w, r, reg, ps.ByName(param), bind(replacer, r), data)
+			})
+		})
+		router.PUT(item, fn)
+	}
+	if deleter, ok := deleterFor(handler); ok {
+		fn := s.route(reg, OperationDelete, item, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+			s.transact(w, r, reg.transaction, func(w http.ResponseWriter, r *http.Request) {
+				s.deleteRequest(w, r, reg, ps.ByName(param), bind(deleter, r))
+			})
+		})
+		router.DELETE(item, fn)
+	}
+	if s.describeOptions {
+		s.addOptionsRoutes(router, reg)
+	}
+}
+
+// SetMaxBodyBytes limits the size of the request body accepted when creating or
+// updating a resource. Larger bodies are rejected with
+// http.StatusRequestEntityTooLarge. A limit of 0 disables the check.
+func (s *Server) SetMaxBodyBytes(n int64) {
+	s.maxBodyBytes = n
+}
+
+// SetMaxBodyBytesFor overrides the body size limit for requests sent with the
+// given Content-Type, such as "multipart/form-data".
+func (s *Server) SetMaxBodyBytesFor(contentType string, n int64) {
+	s.maxBodyBytesFor[strings.ToLower(contentType)] = n
+}
+
+// SetBodyPreprocessor sets a function that is run on create and update requests
+// before the body is parsed. It may replace r.Body and the Content-Type header
+// to convert bodies in formats the server does not understand.
+func (s *Server) SetBodyPreprocessor(fn func(r *http.Request) error) {
+	s.bodyPreprocessor = fn
+}
+
+// parseRequest decodes the body of a create or update request into a new
+// instance of the registered schema.
+func (s *Server) parseRequest(w http.ResponseWriter, r *http.Request, reg *registration) (interface{}, error) {
+	s.limitBody(w, r)
+	if s.bodyPreprocessor != nil {
+		if err := s.bodyPreprocessor(r); err != nil {
+			return nil, err
+		}
+	}
+	if s.requireBody {
+		if err := requireBody(r); err != nil {
+			return nil, err
+		}
+	}
+	if isJSON(r) {
+		return s.parseJSON(r, reg)
+	}
+	return s.parseForm(r, reg)
+}
+
+// SetRequireBody rejects create and update requests with an empty body with
+// http.StatusBadRequest. By default an empty body is bound to a zero valued
+// schema, leaving the resource handler to apply defaults.
+func (s *Server) SetRequireBody(require bool) {
+	s.requireBody = require
+}
+
+// requireBody returns an error if the request body is empty. The body is read
+// ahead by a byte when its length is not known, and restored for parsing.
+func requireBody(r *http.Request) error {
+	if r.Body != nil && r.Body != http.NoBody {
+		br := bufio.NewReader(r.Body)
+		if _, err := br.Peek(1); err == nil {
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{br, r.Body}
+			return nil
 		}
-		s.router.POST("/"+path+"/:id", fn)
 	}
-	if deleter, ok := handler.(Deleter); ok {
-		fn := func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-			s.deleteRequest(w, r, ps.ByName("id"), deleter)
+	return &APIError{
+		Status:  http.StatusBadRequest,
+		Code:    "empty_body",
+		Message: "Request body is required",
+	}
+}
+
+// limitBody wraps the request body in a reader that fails once the limit for
+// the request's Content-Type is exceeded.
+func (s *Server) limitBody(w http.ResponseWriter, r *http.Request) {
+	n := s.maxBodyBytes
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil {
+		if limit, ok := s.maxBodyBytesFor[mediaType]; ok {
+			n = limit
 		}
-		s.router.DELETE("/"+path+"/:id", fn)
+	}
+	if n > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, n)
 	}
 }
 
+// SetMaxURLLength rejects requests whose URL is longer than n bytes with
+// http.StatusRequestURITooLong before they are routed. A limit of 0 disables
+// the check.
+func (s *Server) SetMaxURLLength(n int) {
+	s.maxURLLength = n
+}
+
+// SetStackTraces enables logging a stack trace along with unhandled errors that
+// result in http.StatusInternalServerError. It is off by default.
+func (s *Server) SetStackTraces(enabled bool) {
+	s.stackTraces = enabled
+}
+
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	s.router.ServeHTTP(w, r)
+	w, r, observed := s.observe(w, r)
+	defer observed()
+
+	if !s.beginRequest(w, r) {
+		return
+	}
+	defer s.endRequest()
+
+	r = s.withRequestID(w, r)
+	if !s.checkMaintenance(w, r) || !s.checkReadOnly(w, r) {
+		return
+	}
+
+	if s.maxURLLength > 0 {
+		uri := r.RequestURI
+		if uri == "" {
+			uri = r.URL.RequestURI()
+		}
+		if len(uri) > s.maxURLLength {
+			s.writeError(w, r, statusError(http.StatusRequestURITooLong))
+			return
+		}
+	}
+
+	if !s.limitRate(w, r) {
+		return
+	}
+
+	if len(s.languages) > 0 {
+		w.Header().Add("Vary", "Accept-Language")
+	}
+	r = s.withLanguage(r)
+	r = s.withFlags(r)
+
+	w, done := s.compress(w, r)
+	defer done()
+
+	r = trimTrailingSlash(r)
+	if s.caseInsensitiveRouting {
+		r = s.foldPath(r)
+	}
+	s.router.Load().ServeHTTP(w, r)
 }
 
-func (s *Server) getRequest(w http.ResponseWriter, r *http.Request, id string, getter Getter) {
-	res, err := getter.GetResource(id)
-	if err != nil {
-		s.writeError(w, err)
-	} else {
-		s.writeResource(w, http.StatusOK, res)
+// SetLowercasePaths routes resources at the lowercase form of their path, so
+// that a handler with the path "Users" is served at /users. It applies to
+// resources added after it is set, and to the paths given to Remove.
+func (s *Server) SetLowercasePaths(enabled bool) {
+	s.lowercasePaths = enabled
+}
+
+// SetCaseInsensitiveRouting routes requests whose path only differs from a
+// route by case, such as /Users/Alice for /users/:id, when no route matches
+// the path as sent. The id keeps the case it was sent in. Routes should be
+// lowercase, such as with SetLowercasePaths.
+func (s *Server) SetCaseInsensitiveRouting(enabled bool) {
+	s.caseInsensitiveRouting = enabled
+}
+
+// foldPath returns a copy of the request with its path in the case of the
+// route it matches ignoring case. Requests that match a route as sent, or no
+// route at all, are returned unchanged.
+func (s *Server) foldPath(r *http.Request) *http.Request {
+	path := r.URL.Path
+	lower := strings.ToLower(path)
+	if lower == path {
+		return r
+	}
+
+	router := s.router.Load()
+	var found bool
+	var ps httprouter.Params
+	for _, method := range append([]string{r.Method}, allowOrder...) {
+		if handle, _, _ := router.Lookup(method, path); handle != nil {
+			return r
+		}
+		if handle, params, _ := router.Lookup(method, lower); handle != nil && !found {
+			found, ps = true, params
+		}
+	}
+	if !found {
+		return r
+	}
+
+	// Only the last segment of a route is a parameter
+	if len(ps) > 0 {
+		lower = lower[:strings.LastIndexByte(lower, '/')] + path[strings.LastIndexByte(path, '/'):]
+	}
+	u := *r.URL
+	u.Path = lower
+	u.RawPath = ""
+	r2 := *r
+	r2.URL = &u
+	return &r2
+}
+
+// trimTrailingSlash returns a copy of the request without trailing slashes in
+// its path, so that /users/ is served as /users rather than redirected, which
+// clients do not follow with the body of a create.
+func trimTrailingSlash(r *http.Request) *http.Request {
+	path := r.URL.Path
+	if len(path) <= 1 || !strings.HasSuffix(path, "/") {
+		return r
+	}
+
+	u := *r.URL
+	u.Path = strings.TrimRight(path, "/")
+	if u.Path == "" {
+		u.Path = "/"
 	}
+	u.RawPath = strings.TrimRight(u.RawPath, "/")
+	r2 := *r
+	r2.URL = &u
+	return &r2
 }
 
-func (s *Server) listRequest(w http.ResponseWriter, r *http.Request, lister Lister) {
-	list, err := lister.ListResource()
+func (s *Server) getRequest(w http.ResponseWriter, r *http.Request, reg *registration, id string, getter Getter) {
+	res, err := s.getSharedResource(r, reg, getter, id)
 	if err != nil {
-		s.writeError(w, err)
+		s.writeError(w, r, err)
+		return
+	}
+	if d, ok := asDownload(res); ok {
+		s.writeDownload(w, r, d)
+		return
+	}
+
+	if expander, ok := getter.(Expander); ok {
+		relations, err := s.expandRelations(r, expander)
+		if err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+		if relations != nil {
+			if res, err = expander.Expand(res, relations); err != nil {
+				s.writeError(w, r, err)
+				return
+			}
+		}
+	}
+
+	rep, err := s.represent(reg, getter, res)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	if rep, err = authorizeFields(r, reg, getter, res, rep); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	if etag, err := resourceETag(rep); err == nil && checkNotModified(w, r, etag) {
+		return
+	}
+
+	s.writeResource(w, r, http.StatusOK, rep)
+}
+
+func (s *Server) listRequest(w http.ResponseWriter, r *http.Request, reg *registration, lister Lister) {
+	if reg.listCacheControl != "" {
+		w.Header().Set("Cache-Control", reg.listCacheControl)
+	}
+
+	var etag string
+	if versioner, ok := lister.(ListVersioner); ok {
+		version, err := versioner.ListVersion()
+		if err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+		etag = listETag(r, version)
+		if checkNotModified(w, r, etag) {
+			return
+		}
+	}
+
+	var list []interface{}
+	var err error
+	status := http.StatusOK
+	if paged, ok := lister.(PagedLister); ok {
+		list, status, err = s.listRange(w, r, reg, paged)
+	} else if cursor, ok := lister.(CursorLister); ok {
+		list, err = s.listCursor(w, r, reg, cursor)
+	} else if ql, ok := lister.(QueryLister); ok {
+		var query ListQuery
+		if query, err = s.parseListQuery(r, reg); err == nil {
+			list, err = ql.ListResourceQuery(query)
+		}
 	} else {
-		s.writeResourceList(w, http.StatusOK, list)
+		list, err = lister.ListResource()
+	}
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	expander, _ := lister.(Expander)
+	var relations []string
+	if expander != nil {
+		if relations, err = s.expandRelations(r, expander); err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+	}
+
+	omitted, err := s.omittedFields(r, reg)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
 	}
+
+	// Build the representations in a new list, the handler may own the one
+	// it returned
+	out := make([]interface{}, len(list))
+	for k, res := range list {
+		if r.Context().Err() != nil {
+			return
+		}
+		if relations != nil {
+			if res, err = expander.Expand(res, relations); err != nil {
+				s.writeError(w, r, err)
+				return
+			}
+		}
+		schema := reg.isSchema(res)
+		if out[k], err = s.represent(reg, lister, res); err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+		if out[k], err = authorizeFields(r, reg, lister, res, out[k]); err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+		if omitted != nil && schema {
+			if out[k], err = omitFields(out[k], omitted); err != nil {
+				s.writeError(w, r, err)
+				return
+			}
+		}
+	}
+
+	if etag == "" {
+		if etag, err = resourceETag(out); err == nil && checkNotModified(w, r, weakETag(etag)) {
+			return
+		}
+	}
+
+	s.writeResourceList(w, r, status, out)
 }
 
-func (s *Server) createRequest(w http.ResponseWriter, r *http.Request, creator Creator, data interface{}) {
+func (s *Server) createRequest(w http.ResponseWriter, r *http.Request, reg *registration, creator Creator, data interface{}) {
+	data, err := s.applyFieldRules(reg, data)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	data, err = s.generateID(reg, data)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	data, err = s.stampTimestamps(reg, data, OperationCreate)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	if data, err = s.stampActors(r, reg, data, OperationCreate); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	if err := validate(r, creator, data, OperationCreate); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	if reg.cancelled(r) {
+		return
+	}
 	response, err := creator.CreateResource(data)
 	if err != nil {
-		s.writeError(w, err)
-	} else {
-		s.writeResource(w, http.StatusCreated, response)
+		s.writeError(w, r, err)
+		return
+	}
+	if response == nil {
+		s.notify(reg, OperationCreate, "", nil)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	id, _ := s.resourceID(response)
+	if value, ok := s.itemValue(reg, response, id); ok {
+		w.Header().Set("Location", reg.route+"/"+url.PathEscape(value))
+	}
+
+	rep, err := s.represent(reg, creator, response)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
 	}
+	s.notify(reg, OperationCreate, id, rep)
+
+	if rep, err = authorizeFields(r, reg, creator, response, rep); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	s.writeResource(w, r, http.StatusCreated, rep)
 }
 
-func (s *Server) updateRequest(w http.ResponseWriter, r *http.Request, id string, updater Updater, data interface{}) {
-	res, err := updater.GetResource(id)
+func (s *Server) updateRequest(w http.ResponseWriter, r *http.Request, reg *registration, id string, updater Updater, data interface{}) {
+	if err := s.checkPrecondition(r); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	data, err := s.stampTimestamps(reg, data, OperationUpdate)
 	if err != nil {
-		s.writeError(w, err)
+		s.writeError(w, r, err)
+		return
+	}
+	if data, err = s.stampActors(r, reg, data, OperationUpdate); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	if err := validate(r, updater, data, OperationUpdate); err != nil {
+		s.writeError(w, r, err)
 		return
 	}
 
+	res, err := s.getResource(reg, updater, id)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	if !s.checkIfMatch(w, r, reg, updater, res) {
+		return
+	}
+	if data, err = s.keepImmutableFields(reg, data, res); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	if reg.cancelled(r) {
+		return
+	}
 	response, err := updater.UpdateResource(res, data)
 	if err != nil {
-		s.writeError(w, err)
+		s.writeError(w, r, err)
 		return
 	}
 
-	s.writeResource(w, http.StatusOK, response)
+	rep, err := s.represent(reg, updater, response)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	s.notify(reg, OperationUpdate, id, rep)
+
+	if rep, err = authorizeFields(r, reg, updater, response, rep); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	s.writeResource(w, r, http.StatusOK, rep)
 }
 
-func (s *Server) deleteRequest(w http.ResponseWriter, r *http.Request, id string, deleter Deleter) {
-	res, err := deleter.GetResource(id)
-	if err != nil {
-		s.writeError(w, err)
+func (s *Server) deleteRequest(w http.ResponseWriter, r *http.Request, reg *registration, id string, deleter Getter) {
+	if err := s.checkPrecondition(r); err != nil {
+		s.writeError(w, r, err)
 		return
 	}
 
-	err = deleter.DeleteResource(res)
+	res, err := s.getResource(reg, deleter, id)
 	if err != nil {
-		s.writeError(w, err)
+		s.writeError(w, r, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-}
+	if !s.checkIfMatch(w, r, reg, deleter, res) {
+		return
+	}
 
-func (s *Server) writeResource(w http.ResponseWriter, status int, res interface{}) {
-	out, err := json.Marshal(res)
+	result, err := deleteResource(deleter, res)
 	if err != nil {
-		log.Printf("Failed to marshal resource to JSON: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		s.writeError(w, r, err)
 		return
 	}
+	if s.webhook != nil {
+		if res, err := s.represent(reg, deleter, res); err == nil {
+			s.notify(reg, OperationDelete, id, res)
+		}
+	}
 
+	status := http.StatusOK
+	if result != nil {
+		for key, values := range result.Header {
+			w.Header()[key] = values
+		}
+		if result.Status != 0 {
+			status = result.Status
+		}
+	}
 	w.WriteHeader(status)
-	w.Write(out)
 }
 
-func (s *Server) writeResourceList(w http.ResponseWriter, status int, list []interface{}) {
-	out, err := json.Marshal(list)
+// keepImmutableFields returns a copy of the update data with the immutable
+// fields of the existing resource res. Data is returned as is unless both are
+// of the resource schema.
+func (s *Server) keepImmutableFields(reg *registration, data interface{}, res interface{}) (interface{}, error) {
+	t := reflect.TypeOf(reg.schema)
+	if t == nil || t.Kind() != reflect.Struct || reflect.TypeOf(data) != t || reflect.TypeOf(res) != t {
+		return data, nil
+	}
+
+	val := reflect.New(t).Elem()
+	val.Set(reflect.ValueOf(data))
+	if err := s.copyImmutableFields(reg, val, reflect.ValueOf(res)); err != nil {
+		return nil, err
+	}
+	return val.Interface(), nil
+}
+
+// represent builds the representation of a resource that is written to the
+// client, converting it to the output schema of the registration and applying
+// the representation interfaces implemented by its handler.
+func (s *Server) represent(reg *registration, handler interface{}, res interface{}) (interface{}, error) {
+	var err error
+	if reg.output != nil && indirectType(res) == reflect.TypeOf(reg.schema) {
+		if res, err = convertSchema(res, reg.output); err != nil {
+			return nil, err
+		}
+	}
+	rep := res
+	if s.zeroPolicy != ZeroAsTagged {
+		if rep, err = s.applyZeroPolicy(res); err != nil {
+			return nil, err
+		}
+	}
+	if reg.timeFormat != "" {
+		if rep, err = s.formatTimes(res, rep, reg.timeFormat); err != nil {
+			return nil, err
+		}
+	}
+	if reg.audit {
+		if rep, err = s.auditFields(res, rep); err != nil {
+			return nil, err
+		}
+	}
+	if computed, ok := handler.(Computed); ok {
+		if rep, err = withComputedFields(rep, computed); err != nil {
+			return nil, err
+		}
+	}
+	if actionable, ok := handler.(Actionable); ok {
+		if rep, err = withActions(res, rep, actionable); err != nil {
+			return nil, err
+		}
+	}
+	return rep, nil
+}
+
+// writeResource encodes and writes res. Nothing is written once the request
+// context is done, as the client has gone away.
+func (s *Server) writeResource(w http.ResponseWriter, r *http.Request, status int, res interface{}) {
+	if r.Context().Err() != nil {
+		return
+	}
+
+	out, mediaType, err := s.encodeResource(r, res)
 	if err != nil {
-		log.Printf("Failed to marshal resource to JSON: %v", err)
+		s.logger.Printf("Failed to encode resource as %s: %v", mediaType, err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	if r.Context().Err() != nil {
+		return
+	}
 
+	w.Header().Set("Content-Type", s.contentType(mediaType))
 	w.WriteHeader(status)
-	w.Write(out)
+	writeBody(r, w, out)
 }
 
-func (s *Server) writeError(w http.ResponseWriter, err error) {
-	if err == ErrNotFound {
-		w.WriteHeader(http.StatusNotFound)
-	} else if err != nil {
-		log.Printf("Unhandled error: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+// writeChunkSize is the size of the chunks a body is written in, checking
+// for cancellation between them.
+const writeChunkSize = 32 << 10
+
+// writeBody writes out in chunks, stopping when a write fails or the request
+// context is done.
+func writeBody(r *http.Request, w http.ResponseWriter, out []byte) {
+	for len(out) > 0 {
+		if r.Context().Err() != nil {
+			return
+		}
+		n := len(out)
+		if n > writeChunkSize {
+			n = writeChunkSize
+		}
+		if _, err := w.Write(out[:n]); err != nil {
+			return
+		}
+		out = out[n:]
+	}
+}
+
+func (s *Server) writeResourceList(w http.ResponseWriter, r *http.Request, status int, list []interface{}) {
+	// Always respond with an array, json.Marshal encodes a nil slice as null
+	if list == nil {
+		list = []interface{}{}
+	}
+
+	s.writeResource(w, r, status, list)
+}
+
+// errorStatus returns the status code for err, logging errors that result in
+// http.StatusInternalServerError along with the request they failed.
+func (s *Server) errorStatus(r *http.Request, err error) int {
+	status := errorStatus(err)
+	if status == http.StatusInternalServerError {
+		request := r.Method + " " + r.URL.Path
+		if id := RequestIDFromContext(r.Context()); id != "" {
+			request += ", request " + id
+		}
+		if s.stackTraces {
+			s.logger.Printf("Unhandled error: %v (%s)\n%s", err, request, debug.Stack())
+		} else {
+			s.logger.Printf("Unhandled error: %v (%s)", err, request)
+		}
+	}
+	return status
+}
+
+// clientGone reports whether err was caused by the request context being
+// done. Such errors are not worth logging, as the client has gone away, and
+// there is no one left to write them to.
+func clientGone(r *http.Request, err error) bool {
+	ctxErr := r.Context().Err()
+	return ctxErr != nil && errors.Is(err, ctxErr)
+}
+
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	if err == nil {
+		return
+	}
+	if clientGone(r, err) {
+		return
 	}
+
+	status := s.errorStatus(r, err)
+
+	apiErr, ok := asAPIError(err)
+	if !ok && s.errorBodies && !s.problemJSON {
+		apiErr, ok = statusError(status), true
+	}
+	if !ok && !s.problemJSON {
+		w.WriteHeader(status)
+		return
+	}
+
+	var body *APIError
+	if ok {
+		body = new(APIError)
+		*body = *apiErr
+		body.Status = status
+		if language := LanguageFromContext(r.Context()); language != "" && s.translator != nil {
+			body.Message = s.translator(language, body)
+		}
+	}
+
+	var out []byte
+	if s.problemJSON {
+		out, err = json.Marshal(newProblem(r, status, body))
+	} else {
+		out, err = json.Marshal(errorBody{body})
+	}
+	if err != nil {
+		s.logger.Printf("Failed to marshal error to JSON: %v", err)
+		w.WriteHeader(status)
+		return
+	}
+
+	if s.problemJSON {
+		w.Header().Set("Content-Type", s.contentType("application/problem+json"))
+	} else {
+		w.Header().Set("Content-Type", s.contentType("application/json"))
+	}
+	w.WriteHeader(status)
+	w.Write(out)
 }
@@ -1,12 +1,17 @@
 package reason
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
 	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -66,6 +71,20 @@ func (trh TestResourceHandler) DeleteResource(resource interface{}) error {
 	return nil
 }
 
+type NilListHandler struct{}
+
+func (n NilListHandler) Path() string {
+	return "nil"
+}
+
+func (n NilListHandler) ListResource() ([]interface{}, error) {
+	return nil, nil
+}
+
+func (n NilListHandler) CreateResource(resource interface{}) (interface{}, error) {
+	return nil, nil
+}
+
 type NoHandler struct{}
 
 func (n NoHandler) Path() string {
@@ -79,9 +98,10 @@ func TestGetter(t *testing.T) {
 		Body       string
 	}{
 		{"/test/1", 200, `{"id":1,"name":"The Test"}`},
-		{"/test/3", 404, ``},
-		{"/other/1", 404, ``},
-		{"/no/1", 404, ``},
+		{"/test/1/", 200, `{"id":1,"name":"The Test"}`},
+		{"/test/3", 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`},
+		{"/other/1", 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`},
+		{"/no/1", 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`},
 	}
 
 	s := New()
@@ -120,12 +140,14 @@ func TestLister(t *testing.T) {
 	}{
 		{"/test", 200, `[{"id":1,"name":"The Test"},{"id":2,"name":"The Other"}]`},
 		{"/test/", 200, `[{"id":1,"name":"The Test"},{"id":2,"name":"The Other"}]`},
-		{"/other", 404, ``},
-		{"/no", 404, ``},
+		{"/nil", 200, `[]`},
+		{"/other", 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`},
+		{"/no", 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`},
 	}
 
 	s := New()
 	s.Add(TestResource{}, TestResourceHandler{})
+	s.Add(TestResource{}, NilListHandler{})
 	s.Add(TestResource{}, NoHandler{})
 	ts := httptest.NewServer(s)
 	defer ts.Close()
@@ -163,13 +185,15 @@ func TestCreator(t *testing.T) {
 		Data       url.Values
 	}{
 		{"/test", 201, `{"id":3,"name":"New Test"}`, form},
-		{"/test/", 307, ``, form},
-		{"/other", 404, ``, nil},
-		{"/no", 404, ``, nil},
+		{"/test/", 201, `{"id":3,"name":"New Test"}`, form},
+		{"/nil", 204, ``, form},
+		{"/other", 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`, nil},
+		{"/no", 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`, nil},
 	}
 
 	s := New()
 	s.Add(TestResource{}, TestResourceHandler{})
+	s.Add(TestResource{}, NilListHandler{})
 	s.Add(TestResource{}, NoHandler{})
 	ts := httptest.NewServer(s)
 	defer ts.Close()
@@ -207,9 +231,9 @@ func TestUpdater(t *testing.T) {
 		Data       url.Values
 	}{
 		{"/test/1", 200, `{"id":1,"name":"Updated Test"}`, form},
-		{"/test/3", 404, ``, form},
-		{"/other", 404, ``, nil},
-		{"/no", 404, ``, nil},
+		{"/test/3", 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`, form},
+		{"/other", 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`, nil},
+		{"/no", 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`, nil},
 	}
 
 	s := New()
@@ -247,9 +271,9 @@ func TestDeleter(t *testing.T) {
 		Body       string
 	}{
 		{"/test/1", 200, ``},
-		{"/test/3", 404, ``},
-		{"/other", 404, ``},
-		{"/no", 404, ``},
+		{"/test/3", 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`},
+		{"/other", 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`},
+		{"/no", 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`},
 	}
 
 	s := New()
@@ -286,3 +310,603 @@ func TestDeleter(t *testing.T) {
 		}
 	}
 }
+
+func TestGetETag(t *testing.T) {
+	etag, err := resourceETag(testData[0])
+	if err != nil {
+		t.Fatalf("expected no error from resourceETag, got %s", err.Error())
+	}
+
+	var requests = []struct {
+		IfNoneMatch string
+		StatusCode  int
+	}{
+		{"", 200},
+		{etag, 304},
+		{"W/" + etag, 304},
+		{`"stale"`, 200},
+	}
+
+	s := New()
+	s.Add(TestResource{}, TestResourceHandler{})
+
+	for _, request := range requests {
+		req := httptest.NewRequest("GET", "/test/1", nil)
+		if request.IfNoneMatch != "" {
+			req.Header.Set("If-None-Match", request.IfNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		if rec.Code != request.StatusCode {
+			t.Errorf("If-None-Match %s: expected status code %d, got %d", request.IfNoneMatch, request.StatusCode, rec.Code)
+		}
+		if tag := rec.Header().Get("ETag"); tag != etag {
+			t.Errorf("If-None-Match %s: expected strong ETag '%s', got '%s'", request.IfNoneMatch, etag, tag)
+		}
+	}
+}
+
+func TestNotModifiedHeaders(t *testing.T) {
+	s := New()
+	s.SetCompression(true)
+	s.SetLanguages("en", "pt")
+	s.RegisterEncoder("text/csv", encodeTestCSV)
+	s.Add(TestResource{}, TestResourceHandler{}, WithListCacheControl("private, max-age=60"))
+
+	for _, path := range []string{"/test", "/test/1"} {
+		get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest("GET", path, nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			if ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", ifNoneMatch)
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+			return rec
+		}
+
+		ok := get("")
+		etag := ok.Header().Get("ETag")
+		notModified := get(etag)
+		if notModified.Code != http.StatusNotModified {
+			t.Fatalf("%s: expected status code 304, got %d", path, notModified.Code)
+		}
+		if notModified.Body.Len() != 0 {
+			t.Errorf("%s: expected no body, got '%s'", path, notModified.Body.String())
+		}
+		for _, name := range []string{"ETag", "Cache-Control", "Vary"} {
+			if expected, got := ok.Header().Values(name), notModified.Header().Values(name); fmt.Sprint(got) != fmt.Sprint(expected) {
+				t.Errorf("%s: expected 304 %s %v, got %v", path, name, expected, got)
+			}
+		}
+		if vary := notModified.Header().Values("Vary"); fmt.Sprint(vary) != "[Accept-Language Accept-Encoding Accept]" {
+			t.Errorf("%s: expected Vary of every negotiated header, got %v", path, vary)
+		}
+		if encoding := notModified.Header().Get("Content-Encoding"); encoding != "" {
+			t.Errorf("%s: expected no Content-Encoding, got '%s'", path, encoding)
+		}
+	}
+}
+
+func TestConditionalDeleter(t *testing.T) {
+	etag, err := resourceETag(testData[0])
+	if err != nil {
+		t.Fatalf("expected no error from resourceETag, got %s", err.Error())
+	}
+
+	var requests = []struct {
+		Path       string
+		IfMatch    string
+		StatusCode int
+	}{
+		{"/test/1", etag, 200},
+		{"/test/1", `"other", ` + etag, 200},
+		{"/test/1", "*", 200},
+		{"/test/1", `"stale"`, 412},
+		{"/test/1", "W/" + etag, 412},
+		{"/test/3", etag, 404},
+	}
+
+	s := New()
+	s.Add(TestResource{}, TestResourceHandler{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	client := &http.Client{}
+
+	for _, request := range requests {
+		req, err := http.NewRequest("DELETE", ts.URL+request.Path, nil)
+		if err != nil {
+			t.Errorf("%s: expected no error from http.NewRequest, got %s", request.Path, err.Error())
+		}
+		req.Header.Set("If-Match", request.IfMatch)
+
+		res, err := client.Do(req)
+		if err != nil {
+			t.Errorf("%s: expected no error from client.Do, got %s", request.Path, err.Error())
+		}
+		res.Body.Close()
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s (If-Match %s): expected status code %d, got %d", request.Path, request.IfMatch, request.StatusCode, res.StatusCode)
+		}
+	}
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+	form := url.Values{}
+	form.Add("name", strings.Repeat("a", 64))
+
+	var requests = []struct {
+		Limit      int64
+		FormLimit  int64
+		StatusCode int
+	}{
+		{0, 0, 201},
+		{1024, 0, 201},
+		{16, 0, 413},
+		{16, 1024, 201},
+		{1024, 16, 413},
+	}
+
+	for _, request := range requests {
+		s := New()
+		s.Add(TestResource{}, TestResourceHandler{})
+		s.SetMaxBodyBytes(request.Limit)
+		if request.FormLimit > 0 {
+			s.SetMaxBodyBytesFor("application/x-www-form-urlencoded", request.FormLimit)
+		}
+		ts := httptest.NewServer(s)
+
+		res, err := http.PostForm(ts.URL+"/test", form)
+		if err != nil {
+			t.Errorf("%d/%d: expected no error from PostForm, got %s", request.Limit, request.FormLimit, err.Error())
+		}
+		res.Body.Close()
+		ts.Close()
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%d/%d: expected status code %d, got %d", request.Limit, request.FormLimit, request.StatusCode, res.StatusCode)
+		}
+	}
+}
+
+func TestMaxURLLength(t *testing.T) {
+	var requests = []struct {
+		Path       string
+		StatusCode int
+	}{
+		{"/test/1", 200},
+		{"/test/1?q=" + strings.Repeat("a", 8), 200},
+		{"/test/1?q=" + strings.Repeat("a", 9), 414},
+		{"/test/" + strings.Repeat("1", 14), 414},
+	}
+
+	s := New()
+	s.Add(TestResource{}, TestResourceHandler{})
+	s.SetMaxURLLength(18)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		res, err := http.Get(ts.URL + request.Path)
+		if err != nil {
+			t.Errorf("%s: expected no error from Get, got %s", request.Path, err.Error())
+		}
+		res.Body.Close()
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Path, request.StatusCode, res.StatusCode)
+		}
+	}
+}
+
+type FailingHandler struct{}
+
+func (f FailingHandler) Path() string {
+	return "fail"
+}
+
+func (f FailingHandler) GetResource(id string) (interface{}, error) {
+	return nil, fmt.Errorf("Failed to get %s", id)
+}
+
+func TestStackTraces(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	for _, enabled := range []bool{false, true} {
+		buf.Reset()
+
+		s := New()
+		s.Add(TestResource{}, FailingHandler{})
+		s.SetStackTraces(enabled)
+		ts := httptest.NewServer(s)
+
+		res, err := http.Get(ts.URL + "/fail/1")
+		if err != nil {
+			t.Errorf("%t: expected no error from Get, got %s", enabled, err.Error())
+		}
+		res.Body.Close()
+		ts.Close()
+
+		if res.StatusCode != 500 {
+			t.Errorf("%t: expected status code 500, got %d", enabled, res.StatusCode)
+		}
+		if !strings.Contains(buf.String(), "Unhandled error: Failed to get 1") {
+			t.Errorf("%t: expected error to be logged, got '%s'", enabled, buf.String())
+		}
+		if strings.Contains(buf.String(), "writeError") != enabled {
+			t.Errorf("%t: expected stack trace logged to be %t, got '%s'", enabled, enabled, buf.String())
+		}
+	}
+}
+
+func TestBodyPreprocessor(t *testing.T) {
+	s := New()
+	s.Add(TestResource{}, TestResourceHandler{})
+	s.SetBodyPreprocessor(func(r *http.Request) error {
+		if r.Header.Get("Content-Type") != "text/plain" {
+			return nil
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		form := url.Values{}
+		for _, line := range strings.Split(string(body), "\n") {
+			if idx := strings.Index(line, ":"); idx != -1 {
+				form.Set(line[0:idx], line[idx+1:])
+			}
+		}
+
+		r.Body = ioutil.NopCloser(strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return nil
+	})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/test", "text/plain", strings.NewReader("name:Plain Test"))
+	if err != nil {
+		t.Errorf("expected no error from Post, got %s", err.Error())
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Errorf("expected no error from read, got %s", err.Error())
+	}
+
+	if res.StatusCode != 201 {
+		t.Errorf("expected status code 201, got %d", res.StatusCode)
+	}
+	if string(body) != `{"id":3,"name":"Plain Test"}` {
+		t.Errorf("expected body '{\"id\":3,\"name\":\"Plain Test\"}', got '%s'", body)
+	}
+}
+
+func TestRequireBody(t *testing.T) {
+	var requests = []struct {
+		RequireBody bool
+		Method      string
+		Path        string
+		ContentType string
+		Body        string
+		StatusCode  int
+	}{
+		{false, "POST", "/test", "application/x-www-form-urlencoded", "", 201},
+		{true, "POST", "/test", "application/x-www-form-urlencoded", "", 400},
+		{true, "POST", "/test/1", "application/x-www-form-urlencoded", "", 400},
+		{true, "POST", "/test", "application/json", "", 400},
+		{true, "POST", "/test", "application/x-www-form-urlencoded", "name=Required", 201},
+		{true, "POST", "/test", "application/json", `{"name":"Required"}`, 201},
+	}
+
+	for _, request := range requests {
+		s := New()
+		s.SetRequireBody(request.RequireBody)
+		s.Add(TestResource{}, TestResourceHandler{})
+		ts := httptest.NewServer(s)
+
+		res, err := http.Post(ts.URL+request.Path, request.ContentType, strings.NewReader(request.Body))
+		if err != nil {
+			t.Fatalf("%s %s: expected no error from Post, got %s", request.Method, request.Path, err.Error())
+		}
+		res.Body.Close()
+		ts.Close()
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s %s '%s': expected status code %d, got %d", request.Method, request.Path, request.Body, request.StatusCode, res.StatusCode)
+		}
+	}
+}
+
+func TestRequirePrecondition(t *testing.T) {
+	etag, err := resourceETag(testData[0])
+	if err != nil {
+		t.Fatalf("expected no error from resourceETag, got %s", err.Error())
+	}
+
+	var requests = []struct {
+		Require    bool
+		Method     string
+		IfMatch    string
+		StatusCode int
+	}{
+		{false, "POST", "", 200},
+		{false, "DELETE", "", 200},
+		{true, "POST", "", 428},
+		{true, "DELETE", "", 428},
+		{true, "POST", etag, 200},
+		{true, "DELETE", etag, 200},
+		{true, "POST", `"stale"`, 412},
+		{true, "DELETE", `"stale"`, 412},
+	}
+
+	for _, request := range requests {
+		s := New()
+		s.SetRequirePrecondition(request.Require)
+		s.Add(TestResource{}, TestResourceHandler{})
+		ts := httptest.NewServer(s)
+
+		req, err := http.NewRequest(request.Method, ts.URL+"/test/1", strings.NewReader("name=The+Test"))
+		if err != nil {
+			t.Fatalf("%s: expected no error from http.NewRequest, got %s", request.Method, err.Error())
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if request.IfMatch != "" {
+			req.Header.Set("If-Match", request.IfMatch)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: expected no error from client.Do, got %s", request.Method, err.Error())
+		}
+		res.Body.Close()
+		ts.Close()
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s (If-Match %s): expected status code %d, got %d", request.Method, request.IfMatch, request.StatusCode, res.StatusCode)
+		}
+	}
+}
+
+type QueuedDeleteHandler struct {
+	TestResourceHandler
+}
+
+func (qdh QueuedDeleteHandler) Path() string {
+	return "queued"
+}
+
+func (qdh QueuedDeleteHandler) DeleteResourceResult(resource interface{}) (*DeleteResult, error) {
+	if resource.(TestResource).ID == 2 {
+		return nil, nil
+	}
+	return &DeleteResult{
+		Status: http.StatusAccepted,
+		Header: http.Header{"Location": []string{"/jobs/1"}},
+	}, nil
+}
+
+func TestResultDeleter(t *testing.T) {
+	var requests = []struct {
+		Path       string
+		StatusCode int
+		Location   string
+	}{
+		{"/queued/1", 202, "/jobs/1"},
+		{"/queued/2", 200, ""},
+		{"/queued/3", 404, ""},
+		{"/test/1", 200, ""},
+	}
+
+	s := New()
+	s.Add(TestResource{}, QueuedDeleteHandler{})
+	s.Add(TestResource{}, TestResourceHandler{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		req, err := http.NewRequest("DELETE", ts.URL+request.Path, nil)
+		if err != nil {
+			t.Fatalf("%s: expected no error from http.NewRequest, got %s", request.Path, err.Error())
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: expected no error from client.Do, got %s", request.Path, err.Error())
+		}
+		res.Body.Close()
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Path, request.StatusCode, res.StatusCode)
+		}
+		if location := res.Header.Get("Location"); location != request.Location {
+			t.Errorf("%s: expected Location '%s', got '%s'", request.Path, request.Location, location)
+		}
+	}
+}
+
+func TestCancelledWrite(t *testing.T) {
+	s := New()
+	s.Add(TestResource{}, TestResourceHandler{})
+
+	for _, path := range []string{"/test", "/test/1"} {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		req := httptest.NewRequest("GET", path, nil).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		if rec.Body.Len() != 0 {
+			t.Errorf("%s: expected nothing written for a cancelled request, got '%s'", path, rec.Body.String())
+		}
+	}
+}
+
+type ListVersionHandler struct {
+	TestResourceHandler
+}
+
+func (lvh ListVersionHandler) Path() string {
+	return "versioned"
+}
+
+func (lvh ListVersionHandler) ListVersion() (string, error) {
+	return "v1", nil
+}
+
+func TestListETag(t *testing.T) {
+	s := New()
+	s.Add(TestResource{}, TestResourceHandler{}, WithListCacheControl("no-cache"))
+	s.Add(TestResource{}, ListVersionHandler{}, WithListCacheControl("no-cache"))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	get := func(path string, ifNoneMatch string) (int, string) {
+		req, err := http.NewRequest("GET", ts.URL+path, nil)
+		if err != nil {
+			t.Fatalf("%s: expected no error from http.NewRequest, got %s", path, err.Error())
+		}
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: expected no error from client.Do, got %s", path, err.Error())
+		}
+		res.Body.Close()
+
+		if cacheControl := res.Header.Get("Cache-Control"); cacheControl != "no-cache" {
+			t.Errorf("%s: expected Cache-Control 'no-cache', got '%s'", path, cacheControl)
+		}
+		return res.StatusCode, res.Header.Get("ETag")
+	}
+
+	strong, err := resourceETag(testData)
+	if err != nil {
+		t.Fatalf("expected no error from resourceETag, got %s", err.Error())
+	}
+	etag := weakETag(strong)
+	_, versioned := get("/versioned", "")
+	_, sorted := get("/versioned?sort=name", "")
+	if !strings.HasPrefix(versioned, "W/") || versioned == sorted {
+		t.Fatalf("expected distinct ETags for versioned lists, got '%s' and '%s'", versioned, sorted)
+	}
+
+	var requests = []struct {
+		Path        string
+		IfNoneMatch string
+		StatusCode  int
+		ETag        string
+	}{
+		{"/test", "", 200, etag},
+		{"/test", etag, 304, etag},
+		{"/test", `"other", ` + strong, 304, etag},
+		{"/test", `"stale"`, 200, etag},
+		{"/versioned", versioned, 304, versioned},
+		{"/versioned", "*", 304, versioned},
+		{"/versioned?sort=name", versioned, 200, sorted},
+	}
+
+	for _, request := range requests {
+		status, tag := get(request.Path, request.IfNoneMatch)
+		if status != request.StatusCode {
+			t.Errorf("%s (If-None-Match %s): expected status code %d, got %d", request.Path, request.IfNoneMatch, request.StatusCode, status)
+		}
+		if tag != request.ETag {
+			t.Errorf("%s: expected ETag '%s', got '%s'", request.Path, request.ETag, tag)
+		}
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	var requests = []struct {
+		Method string
+		Path   string
+		Allow  string
+	}{
+		{"PATCH", "/test", "GET, HEAD, POST, PUT, OPTIONS"},
+		{"PUT", "/test/1", "GET, HEAD, POST, DELETE, OPTIONS"},
+		{"DELETE", "/flags/1", "GET, HEAD, OPTIONS"},
+	}
+
+	s := New()
+	s.Add(TestResource{}, TestResourceHandler{})
+	s.Add(TestResource{}, FlagsHandler{})
+
+	for _, request := range requests {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(request.Method, request.Path, nil))
+
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s %s: expected status code 405, got %d", request.Method, request.Path, rec.Code)
+		}
+		if allow := rec.Header().Get("Allow"); allow != request.Allow {
+			t.Errorf("%s %s: expected Allow '%s', got '%s'", request.Method, request.Path, request.Allow, allow)
+		}
+		if body, expected := rec.Body.String(), `{"error":{"status":405,"code":"method_not_allowed","message":"Method not allowed"}}`; body != expected {
+			t.Errorf("%s %s: expected body '%s', got '%s'", request.Method, request.Path, expected, body)
+		}
+	}
+}
+
+type CasedHandler struct{}
+
+func (ch CasedHandler) Path() string {
+	return "Cased"
+}
+
+func (ch CasedHandler) GetResource(id string) (interface{}, error) {
+	return TestResource{ID: 1, Name: id}, nil
+}
+
+func TestPathCase(t *testing.T) {
+	var requests = []struct {
+		Lowercase       bool
+		CaseInsensitive bool
+		Path            string
+		StatusCode      int
+		Body            string
+	}{
+		{false, false, "/Cased/Alice", 200, `{"id":1,"name":"Alice"}`},
+		{false, false, "/cased/Alice", 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`},
+		{true, false, "/cased/Alice", 200, `{"id":1,"name":"Alice"}`},
+		{true, false, "/Cased/Alice", 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`},
+		{true, true, "/CASED/Alice", 200, `{"id":1,"name":"Alice"}`},
+		{true, true, "/Test/1", 200, `{"id":1,"name":"The Test"}`},
+		{true, true, "/Test", 200, `[{"id":1,"name":"The Test"},{"id":2,"name":"The Other"}]`},
+		{true, true, "/Other/1", 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`},
+	}
+
+	for _, request := range requests {
+		s := New()
+		s.SetLowercasePaths(request.Lowercase)
+		s.SetCaseInsensitiveRouting(request.CaseInsensitive)
+		s.Add(TestResource{}, CasedHandler{})
+		s.Add(TestResource{}, TestResourceHandler{})
+
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest("GET", request.Path, nil))
+
+		if rec.Code != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Path, request.StatusCode, rec.Code)
+		}
+		if body := rec.Body.String(); body != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Path, request.Body, body)
+		}
+	}
+
+	s := New()
+	s.SetLowercasePaths(true)
+	s.Add(TestResource{}, CasedHandler{})
+	if !s.Remove("Cased") {
+		t.Errorf("expected Remove to find the resource by its path as added")
+	}
+}
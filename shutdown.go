@@ -0,0 +1,63 @@
+package reason
+
+import (
+	"context"
+	"net/http"
+)
+
+// Shutdown drains the server: requests arriving after Shutdown is called are
+// answered with http.StatusServiceUnavailable and a Retry-After header, so a
+// load balancer can send them elsewhere. Shutdown waits for in-flight requests
+// to complete, or returns the context's error if it is done first.
+//
+// Shutdown does not close any listener, it is meant to be called before
+// shutting down the http.Server serving s.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.drainLock.Lock()
+	if !s.draining {
+		s.draining = true
+		s.drained = make(chan struct{})
+		if s.inflight == 0 {
+			close(s.drained)
+		}
+	}
+	drained := s.drained
+	s.drainLock.Unlock()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// beginRequest counts a request as in flight, writing the response and
+// returning false when the server is draining.
+func (s *Server) beginRequest(w http.ResponseWriter, r *http.Request) bool {
+	s.drainLock.Lock()
+	defer s.drainLock.Unlock()
+
+	if s.draining {
+		w.Header().Set("Connection", "close")
+		w.Header().Set("Retry-After", "1")
+		s.writeError(w, r, &APIError{
+			Status:  http.StatusServiceUnavailable,
+			Code:    "shutting_down",
+			Message: "The server is shutting down",
+		})
+		return false
+	}
+	s.inflight++
+	return true
+}
+
+func (s *Server) endRequest() {
+	s.drainLock.Lock()
+	defer s.drainLock.Unlock()
+
+	s.inflight--
+	if s.draining && s.inflight == 0 {
+		close(s.drained)
+	}
+}
@@ -0,0 +1,88 @@
+package reason
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type BlockingHandler struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (bh BlockingHandler) Path() string {
+	return "block"
+}
+
+func (bh BlockingHandler) GetResource(id string) (interface{}, error) {
+	bh.started <- struct{}{}
+	<-bh.release
+	return testData[0], nil
+}
+
+func TestShutdown(t *testing.T) {
+	handler := BlockingHandler{make(chan struct{}), make(chan struct{})}
+
+	s := New()
+	s.Add(TestResource{}, handler)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	inflight := make(chan int)
+	go func() {
+		res, err := http.Get(ts.URL + "/block/1")
+		if err != nil {
+			t.Errorf("expected no error from Get, got %s", err.Error())
+			inflight <- 0
+			return
+		}
+		res.Body.Close()
+		inflight <- res.StatusCode
+	}()
+	<-handler.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	if err := s.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected Shutdown to time out, got %v", err)
+	}
+	cancel()
+
+	res, err := http.Get(ts.URL + "/block/1")
+	if err != nil {
+		t.Fatalf("expected no error from Get, got %s", err.Error())
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Errorf("expected no error from read, got %s", err.Error())
+	}
+	if res.StatusCode != 503 {
+		t.Errorf("expected status code 503 while draining, got %d", res.StatusCode)
+	}
+	if string(body) != `{"error":{"status":503,"code":"shutting_down","message":"The server is shutting down"}}` {
+		t.Errorf("expected a shutting down error body, got '%s'", body)
+	}
+	if contentType := res.Header.Get("Content-Type"); contentType != "application/json; charset=utf-8" {
+		t.Errorf("expected Content-Type 'application/json; charset=utf-8', got '%s'", contentType)
+	}
+	if retryAfter := res.Header.Get("Retry-After"); retryAfter != "1" {
+		t.Errorf("expected Retry-After '1', got '%s'", retryAfter)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- s.Shutdown(context.Background())
+	}()
+	close(handler.release)
+
+	if status := <-inflight; status != 200 {
+		t.Errorf("expected in-flight request to complete with 200, got %d", status)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("expected no error from Shutdown, got %s", err.Error())
+	}
+}
@@ -0,0 +1,134 @@
+package reason
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SetSingleFlight coalesces concurrent identical GET requests for a single
+// resource into one call of the handler, sharing its result between them, to
+// reduce the load of popular resources on the datastore. Requests are
+// identical when they have the same path, language and feature flags. Requests
+// with credentials are never coalesced, as their result may depend on the
+// caller, nor are downloads, which can only be read once. Requests that share
+// a call each get their own shallow copy of the resource. The shared call is
+// not cancelled when the client that made it goes away.
+func (s *Server) SetSingleFlight(enabled bool) {
+	if enabled {
+		s.flights = &flightGroup{}
+	} else {
+		s.flights = nil
+	}
+}
+
+// flightGroup coalesces concurrent calls with the same key into one.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	wg   sync.WaitGroup
+	dups int
+	res  interface{}
+	err  error
+}
+
+// do calls fn once for all concurrent callers with the same key. shared
+// reports whether the result came from the call of another caller.
+func (g *flightGroup) do(key string, fn func() (interface{}, error)) (res interface{}, shared bool, err error) {
+	g.mu.Lock()
+	if c, ok := g.calls[key]; ok {
+		c.dups++
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.res, true, c.err
+	}
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	c := new(flightCall)
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		c.wg.Done()
+	}()
+	c.res, c.err = fn()
+	return c.res, false, c.err
+}
+
+// getSharedResource looks up a resource for a GET request, sharing the call
+// of the getter with concurrent identical requests when single flight is
+// enabled.
+func (s *Server) getSharedResource(r *http.Request, reg *registration, getter Getter, id string) (interface{}, error) {
+	if s.flights == nil || r.Method != http.MethodGet || hasCredentials(r) {
+		return s.getResource(reg, getter, id)
+	}
+
+	res, shared, err := s.flights.do(flightKey(r), func() (interface{}, error) {
+		// The call is shared, so it is not cancelled with the request that
+		// happens to make it
+		detached := r.WithContext(context.WithoutCancel(r.Context()))
+		return s.getResource(reg, bind(reg.handler.(Getter), detached), id)
+	})
+	if shared && err != nil && r.Context().Err() == nil && isContextError(err) {
+		return s.getResource(reg, getter, id)
+	}
+	if !shared || err != nil {
+		return res, err
+	}
+	if _, ok := asDownload(res); ok {
+		return s.getResource(reg, getter, id)
+	}
+	return shallowCopy(res), nil
+}
+
+// flightKey returns the key of the requests a GET request can share a call
+// with: those for the same path, language and feature flags.
+func flightKey(r *http.Request) string {
+	key := r.Method + " " + r.URL.Path + "\n" + LanguageFromContext(r.Context())
+	if flags := FlagsFromContext(r.Context()); len(flags) > 0 {
+		names := make([]string, 0, len(flags))
+		for flag, enabled := range flags {
+			if enabled {
+				names = append(names, flag)
+			}
+		}
+		sort.Strings(names)
+		key += "\n" + strings.Join(names, ",")
+	}
+	return key
+}
+
+// isContextError reports whether err was caused by a context being done.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// hasCredentials reports whether a request carries credentials that the
+// resource returned to it may depend on.
+func hasCredentials(r *http.Request) bool {
+	return r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "" || ClaimsFromContext(r.Context()) != nil
+}
+
+// shallowCopy returns a copy of res, copying the value a pointer points to so
+// that fields set on the copy do not change the original.
+func shallowCopy(res interface{}) interface{} {
+	v := reflect.ValueOf(res)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return res
+	}
+	c := reflect.New(v.Elem().Type())
+	c.Elem().Set(v.Elem())
+	return c.Interface()
+}
@@ -0,0 +1,113 @@
+package reason
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// waitForDups waits until n requests are waiting on the call for key.
+func waitForDups(t *testing.T, g *flightGroup, key string, n int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		g.mu.Lock()
+		c, ok := g.calls[key]
+		dups := 0
+		if ok {
+			dups = c.dups
+		}
+		g.mu.Unlock()
+		if dups == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected %d requests waiting on %s", n, key)
+}
+
+// ContextBlockingHandler fails with the error of the context it is bound to
+// once released.
+type ContextBlockingHandler struct {
+	BlockingHandler
+	ctx context.Context
+}
+
+func (ch ContextBlockingHandler) WithContext(ctx context.Context) ResourceHandler {
+	ch.ctx = ctx
+	return ch
+}
+
+func (ch ContextBlockingHandler) GetResource(id string) (interface{}, error) {
+	res, err := ch.BlockingHandler.GetResource(id)
+	if ch.ctx != nil && ch.ctx.Err() != nil {
+		return nil, ch.ctx.Err()
+	}
+	return res, err
+}
+
+func TestSingleFlight(t *testing.T) {
+	handler := BlockingHandler{make(chan struct{}), make(chan struct{})}
+
+	s := New()
+	s.Add(TestResource{}, ContextBlockingHandler{BlockingHandler: handler})
+	s.SetSingleFlight(true)
+	s.SetFlagsHeader("X-Feature-Flags")
+
+	get := func(ctx context.Context, path string, header http.Header) chan string {
+		done := make(chan string, 1)
+		go func() {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", path, nil).WithContext(ctx)
+			for name := range header {
+				r.Header.Set(name, header.Get(name))
+			}
+			s.ServeHTTP(w, r)
+			done <- w.Body.String()
+		}()
+		return done
+	}
+
+	// The first request gives up before the shared call completes
+	ctx, cancel := context.WithCancel(context.Background())
+	first := get(ctx, "/block/1", nil)
+	<-handler.started
+	var responses []chan string
+	for i := 0; i < 3; i++ {
+		responses = append(responses, get(context.Background(), "/block/1", nil))
+	}
+	waitForDups(t, s.flights, "GET /block/1\n", 3)
+	cancel()
+
+	// Requests for other resources, with credentials or with other flags call
+	// the handler
+	responses = append(responses, get(context.Background(), "/block/2", nil))
+	<-handler.started
+	responses = append(responses, get(context.Background(), "/block/1", http.Header{"Authorization": {"Bearer token"}}))
+	<-handler.started
+	responses = append(responses, get(context.Background(), "/block/1", http.Header{"X-Feature-Flags": {"beta"}}))
+	<-handler.started
+
+	close(handler.release)
+	if body := <-first; body != "" {
+		t.Errorf("expected nothing to be written to the cancelled request, got '%s'", body)
+	}
+	for k, done := range responses {
+		if body := <-done; body != `{"id":1,"name":"The Test"}` {
+			t.Errorf("%d: expected the resource, got '%s'", k, body)
+		}
+	}
+}
+
+func TestShallowCopy(t *testing.T) {
+	res := &TestResource{ID: 1, Name: "The Test"}
+	c := shallowCopy(res).(*TestResource)
+	c.Name = "Changed"
+	if res.Name != "The Test" {
+		t.Errorf("expected the original to be unchanged, got %s", res.Name)
+	}
+	if v := shallowCopy(*res); v != *res {
+		t.Errorf("expected values to be returned as is, got %v", v)
+	}
+}
@@ -0,0 +1,176 @@
+package reason
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// MapStore is an in-memory resource handler implementing Getter, Lister,
+// Creator, Updater and Deleter. Resources are keyed by their id field, which is
+// the field tagged `json:"id"` or, failing that, the field named ID. Resources
+// created with an empty id are assigned the next number in sequence.
+//
+// MapStore is intended for examples, tests and prototypes:
+//
+//	s.Add(User{}, reason.NewMapStore("users"))
+type MapStore struct {
+	path string
+
+	lock   sync.RWMutex
+	items  map[string]interface{}
+	keys   []string
+	nextID int64
+}
+
+// NewMapStore creates an empty MapStore served under path.
+func NewMapStore(path string) *MapStore {
+	return &MapStore{
+		path:  path,
+		items: make(map[string]interface{}),
+	}
+}
+
+// Path implements ResourceHandler.
+func (m *MapStore) Path() string {
+	return m.path
+}
+
+// GetResource implements Getter.
+func (m *MapStore) GetResource(id string) (interface{}, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	res, ok := m.items[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return res, nil
+}
+
+// ListResource implements Lister, returning resources in the order they were
+// created.
+func (m *MapStore) ListResource() ([]interface{}, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	list := make([]interface{}, len(m.keys))
+	for k, key := range m.keys {
+		list[k] = m.items[key]
+	}
+	return list, nil
+}
+
+// CreateResource implements Creator.
+func (m *MapStore) CreateResource(resource interface{}) (interface{}, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	val, id, err := storeID(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	// Assign the next unused id in sequence, skipping any ids that were
+	// provided explicitly
+	if id.IsZero() {
+		for {
+			m.nextID++
+			switch id.Kind() {
+			case reflect.String:
+				id.SetString(strconv.FormatInt(m.nextID, 10))
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				id.SetInt(m.nextID)
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				id.SetUint(uint64(m.nextID))
+			}
+			if _, ok := m.items[formatID(id)]; !ok {
+				break
+			}
+		}
+	}
+
+	key := formatID(id)
+	if _, ok := m.items[key]; ok {
+		return nil, NewConflict("id", fmt.Sprintf("Resource %q already exists", key))
+	}
+
+	res := val.Interface()
+	m.items[key] = res
+	m.keys = append(m.keys, key)
+	return res, nil
+}
+
+// UpdateResource implements Updater by replacing resource with data, keeping
+// the id of the existing resource.
+func (m *MapStore) UpdateResource(resource interface{}, data interface{}) (interface{}, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	_, oldID, err := storeID(resource)
+	if err != nil {
+		return nil, err
+	}
+	val, id, err := storeID(data)
+	if err != nil {
+		return nil, err
+	}
+
+	key := formatID(oldID)
+	if _, ok := m.items[key]; !ok {
+		return nil, ErrNotFound
+	}
+
+	id.Set(oldID)
+	res := val.Interface()
+	m.items[key] = res
+	return res, nil
+}
+
+// DeleteResource implements Deleter.
+func (m *MapStore) DeleteResource(resource interface{}) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	_, id, err := storeID(resource)
+	if err != nil {
+		return err
+	}
+
+	key := formatID(id)
+	if _, ok := m.items[key]; !ok {
+		return ErrNotFound
+	}
+
+	delete(m.items, key)
+	for k, v := range m.keys {
+		if v == key {
+			m.keys = append(m.keys[:k], m.keys[k+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// storeID returns a settable copy of resource along with its id field.
+// Resources that are pointers to structs are modified in place.
+func storeID(resource interface{}) (reflect.Value, reflect.Value, error) {
+	val := reflect.ValueOf(resource)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	} else if val.IsValid() {
+		cp := reflect.New(val.Type()).Elem()
+		cp.Set(val)
+		val = cp
+	}
+	if val.Kind() != reflect.Struct {
+		return reflect.Value{}, reflect.Value{}, fmt.Errorf("reason: resource must be a struct, got %T", resource)
+	}
+
+	index, ok := idFieldIndex(val.Type())
+	if !ok {
+		return reflect.Value{}, reflect.Value{}, fmt.Errorf("reason: resource %T has no id field", resource)
+	}
+	return val, val.FieldByIndex(index), nil
+}
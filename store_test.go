@@ -0,0 +1,83 @@
+package reason
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestMapStore(t *testing.T) {
+	first := url.Values{}
+	first.Add("name", "First")
+	second := url.Values{}
+	second.Add("name", "Second")
+	explicit := url.Values{}
+	explicit.Add("id", "3")
+	explicit.Add("name", "Explicit")
+	update := url.Values{}
+	update.Add("id", "9")
+	update.Add("name", "Updated")
+
+	var requests = []struct {
+		Method     string
+		Path       string
+		Data       url.Values
+		StatusCode int
+		Body       string
+	}{
+		{"GET", "/store", nil, 200, `[]`},
+		{"POST", "/store", explicit, 201, `{"id":3,"name":"Explicit"}`},
+		{"POST", "/store", first, 201, `{"id":1,"name":"First"}`},
+		{"POST", "/store", second, 201, `{"id":2,"name":"Second"}`},
+		{"POST", "/store", second, 201, `{"id":4,"name":"Second"}`},
+		{"POST", "/store", explicit, 409, `{"error":{"status":409,"code":"conflict","message":"Resource \"3\" already exists","field":"id"}}`},
+		{"GET", "/store/1", nil, 200, `{"id":1,"name":"First"}`},
+		{"GET", "/store/5", nil, 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`},
+		{"POST", "/store/2", update, 200, `{"id":2,"name":"Updated"}`},
+		{"DELETE", "/store/3", nil, 200, ``},
+		{"DELETE", "/store/3", nil, 404, `{"error":{"status":404,"code":"not_found","message":"Not found"}}`},
+		{"GET", "/store", nil, 200, `[{"id":1,"name":"First"},{"id":2,"name":"Updated"},{"id":4,"name":"Second"}]`},
+	}
+
+	s := New()
+	s.Add(TestResource{}, NewMapStore("store"))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	client := &http.Client{}
+
+	for _, request := range requests {
+		var res *http.Response
+		var err error
+		switch request.Method {
+		case "POST":
+			res, err = http.PostForm(ts.URL+request.Path, request.Data)
+		default:
+			var req *http.Request
+			req, err = http.NewRequest(request.Method, ts.URL+request.Path, nil)
+			if err != nil {
+				t.Fatalf("%s %s: expected no error from http.NewRequest, got %s", request.Method, request.Path, err.Error())
+			}
+			res, err = client.Do(req)
+		}
+		if err != nil {
+			t.Fatalf("%s %s: expected no error from request, got %s", request.Method, request.Path, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s %s: expected status code %d, got %d", request.Method, request.Path, request.StatusCode, res.StatusCode)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s %s: expected no error from read, got %s", request.Method, request.Path, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s %s: expected body '%s', got '%s'", request.Method, request.Path, request.Body, body)
+		}
+	}
+}
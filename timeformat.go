@@ -0,0 +1,86 @@
+package reason
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+// Time formats for WithTimeFormat writing times as numbers of seconds or
+// milliseconds since the Unix epoch.
+const (
+	TimeFormatUnix      = "unix"
+	TimeFormatUnixMilli = "unixmilli"
+)
+
+// WithTimeFormat writes the time.Time and *time.Time fields of the resource in
+// format instead of RFC 3339, for clients that expect another format. The
+// format is a layout for time.Time.Format, such as time.RFC1123, or one of
+// TimeFormatUnix and TimeFormatUnixMilli. It applies to the top level fields
+// of struct resources, nil times are written as null.
+func WithTimeFormat(format string) ResourceOption {
+	return func(reg *registration) {
+		reg.timeFormat = format
+	}
+}
+
+// formatTime returns the representation of t in format.
+func formatTime(t time.Time, format string) interface{} {
+	switch format {
+	case TimeFormatUnix:
+		return t.Unix()
+	case TimeFormatUnixMilli:
+		return t.UnixMilli()
+	}
+	return t.Format(format)
+}
+
+// formatTimes rewrites the time fields of the struct resource res in rep, its
+// representation, in format.
+func (s *Server) formatTimes(res interface{}, rep interface{}, format string) (interface{}, error) {
+	if _, ok := res.(json.Marshaler); ok {
+		return rep, nil
+	}
+	val := reflect.Indirect(reflect.ValueOf(res))
+	if val.Kind() != reflect.Struct {
+		return rep, nil
+	}
+
+	fields, err := s.getSchemaFields(schemaKey{typ: val.Type()})
+	if err != nil {
+		return nil, err
+	}
+
+	var obj *jsonObject
+	for _, field := range fields {
+		if field.typ != timeType && field.typ != reflect.PtrTo(timeType) {
+			continue
+		}
+		if obj == nil {
+			if obj, err = newJSONObject(rep); err != nil {
+				return nil, err
+			}
+		}
+		if _, ok := obj.values[field.name]; !ok {
+			continue
+		}
+
+		value, ok := fieldValue(val, field.index)
+		if !ok {
+			continue
+		}
+		if value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				continue
+			}
+			value = value.Elem()
+		}
+		if err := obj.Set(field.name, formatTime(value.Interface().(time.Time), format)); err != nil {
+			return nil, err
+		}
+	}
+	if obj == nil {
+		return rep, nil
+	}
+	return obj, nil
+}
@@ -0,0 +1,65 @@
+package reason
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type EventResource struct {
+	ID       int64      `json:"id"`
+	Start    time.Time  `json:"start"`
+	End      *time.Time `json:"end"`
+	Reminder *time.Time `json:"reminder,omitempty"`
+}
+
+type EventHandler struct{}
+
+func (eh EventHandler) Path() string {
+	return "events"
+}
+
+func (eh EventHandler) GetResource(id string) (interface{}, error) {
+	start := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	end := start.Add(time.Hour)
+	return EventResource{ID: 1, Start: start, End: &end}, nil
+}
+
+func TestWithTimeFormat(t *testing.T) {
+	var requests = []struct {
+		Path string
+		Body string
+	}{
+		{"/events/1", `{"id":1,"start":"2020-01-02T03:04:05Z","end":"2020-01-02T04:04:05Z"}`},
+		{"/unix/events/1", `{"id":1,"start":1577934245,"end":1577937845}`},
+		{"/milli/events/1", `{"id":1,"start":1577934245000,"end":1577937845000}`},
+		{"/rfc1123/events/1", `{"id":1,"start":"Thu, 02 Jan 2020 03:04:05 UTC","end":"Thu, 02 Jan 2020 04:04:05 UTC"}`},
+	}
+
+	s := New()
+	s.Add(EventResource{}, EventHandler{})
+	s.Add(EventResource{}, EventHandler{}, WithVersion("unix"), WithTimeFormat(TimeFormatUnix))
+	s.Add(EventResource{}, EventHandler{}, WithVersion("milli"), WithTimeFormat(TimeFormatUnixMilli))
+	s.Add(EventResource{}, EventHandler{}, WithVersion("rfc1123"), WithTimeFormat(time.RFC1123))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		res, err := http.Get(ts.URL + request.Path)
+		if err != nil {
+			t.Fatalf("%s: expected no error from request, got %s", request.Path, err.Error())
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Path, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Path, request.Body, body)
+		}
+	}
+}
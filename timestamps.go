@@ -0,0 +1,41 @@
+package reason
+
+import (
+	"reflect"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// SetTimestamps stamps the time.Time fields of parsed resources tagged
+// `reason:"createdAt"` and `reason:"updatedAt"` with the current time before
+// they are passed to the handler. Both are set on create and only updatedAt on
+// update. Fields tagged createdAt are then immutable, so an update keeps the
+// time the resource was created. It is off by default.
+func (s *Server) SetTimestamps(enabled bool) {
+	s.timestamps = enabled
+}
+
+// stampTimestamps returns a copy of data, a parsed resource of the schema of
+// reg, with its timestamp fields set for op.
+func (s *Server) stampTimestamps(reg *registration, data interface{}, op Operation) (interface{}, error) {
+	t := reflect.TypeOf(reg.schema)
+	if !s.timestamps || t == nil || t.Kind() != reflect.Struct || reflect.TypeOf(data) != t {
+		return data, nil
+	}
+
+	fields, err := s.getSchemaFields(schemaKey{typ: t, tag: s.tagName})
+	if err != nil {
+		return nil, err
+	}
+
+	val := reflect.New(t).Elem()
+	val.Set(reflect.ValueOf(data))
+	now := reflect.ValueOf(time.Now())
+	for _, field := range fields {
+		if field.updatedAt || (field.createdAt && op == OperationCreate) {
+			settableField(val, field.index).Set(now)
+		}
+	}
+	return val.Interface(), nil
+}
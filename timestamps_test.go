@@ -0,0 +1,86 @@
+package reason
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type StampedResource struct {
+	ID      int64     `json:"id"`
+	Name    string    `json:"name"`
+	Created time.Time `json:"created" reason:"createdAt"`
+	Updated time.Time `json:"updated" reason:"updatedAt"`
+	Other   string    `json:"other" reason:"updatedAt"`
+}
+
+func TestTimestamps(t *testing.T) {
+	s := New()
+	s.SetTimestamps(true)
+	s.Add(StampedResource{}, NewMapStore("stamped"))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	post := func(path string, data url.Values) StampedResource {
+		res, err := http.PostForm(ts.URL+path, data)
+		if err != nil {
+			t.Fatalf("%s: expected no error from request, got %s", path, err.Error())
+		}
+		defer res.Body.Close()
+
+		var stamped StampedResource
+		if err := json.NewDecoder(res.Body).Decode(&stamped); err != nil {
+			t.Fatalf("%s: expected no error from decode, got %s", path, err.Error())
+		}
+		return stamped
+	}
+
+	start := time.Now()
+	created := post("/stamped", url.Values{"name": {"First"}, "created": {"2000-01-01T00:00:00Z"}})
+	if created.Created.Before(start) || !created.Updated.Equal(created.Created) {
+		t.Errorf("expected created and updated to be stamped at create, got %s and %s", created.Created, created.Updated)
+	}
+	if created.Other != "" {
+		t.Errorf("expected non-time field to be left alone, got '%s'", created.Other)
+	}
+
+	updated := post("/stamped/1", url.Values{"name": {"Second"}, "created": {"2000-01-01T00:00:00Z"}})
+	if !updated.Created.Equal(created.Created) {
+		t.Errorf("expected created to be kept on update, got %s, want %s", updated.Created, created.Created)
+	}
+	if updated.Updated.Before(created.Updated) {
+		t.Errorf("expected updated to be stamped at update, got %s before %s", updated.Updated, created.Updated)
+	}
+}
+
+func TestTimestampsOff(t *testing.T) {
+	s := New()
+	s.Add(StampedResource{}, NewMapStore("stamped"))
+
+	post := func(path string, data url.Values) StampedResource {
+		r := httptest.NewRequest("POST", path, strings.NewReader(data.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+
+		var stamped StampedResource
+		if err := json.NewDecoder(w.Body).Decode(&stamped); err != nil {
+			t.Fatalf("%s: expected no error from decode, got %s", path, err.Error())
+		}
+		return stamped
+	}
+
+	created := post("/stamped", url.Values{"name": {"First"}, "created": {"2000-01-01T00:00:00Z"}})
+	if !created.Updated.IsZero() {
+		t.Errorf("expected updated not to be stamped, got %s", created.Updated)
+	}
+
+	updated := post("/stamped/1", url.Values{"name": {"Second"}, "created": {"2001-01-01T00:00:00Z"}})
+	if want := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC); !updated.Created.Equal(want) {
+		t.Errorf("expected created to be updated without timestamps, got %s, want %s", updated.Created, want)
+	}
+}
@@ -0,0 +1,100 @@
+package reason
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+)
+
+// TransactionFunc begins a transaction for a request. It returns a copy of ctx
+// holding the transaction, which handlers implementing ContextHandler are
+// bound to, and the functions committing or rolling back the transaction.
+type TransactionFunc func(ctx context.Context) (tx context.Context, commit func() error, rollback func() error, err error)
+
+// SetTransaction runs every bulk create, and the creates, updates and deletes
+// of resources added with WithTransaction, in a transaction begun by begin.
+// The transaction is committed when the request succeeds and rolled back when
+// it fails, so that a bulk create is all or nothing. The response is held
+// until the transaction is committed, a failed commit is written as an
+// internal server error instead. Handlers must implement ContextHandler to use
+// the transaction.
+func (s *Server) SetTransaction(begin TransactionFunc) {
+	s.transaction = begin
+}
+
+// WithTransaction runs the creates, updates and deletes of the resource in a
+// transaction begun by the function set with SetTransaction.
+func WithTransaction() ResourceOption {
+	return func(reg *registration) {
+		reg.transaction = true
+	}
+}
+
+// inTransaction reports whether the request is served in a transaction.
+func inTransaction(r *http.Request) bool {
+	tx, _ := r.Context().Value(transactionKey).(bool)
+	return tx
+}
+
+// transactionWriter holds the status and body of a response until the
+// transaction of the request is committed or rolled back.
+type transactionWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (tw *transactionWriter) WriteHeader(status int) {
+	if tw.status == 0 {
+		tw.status = status
+	}
+}
+
+func (tw *transactionWriter) Write(b []byte) (int, error) {
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	return tw.body.Write(b)
+}
+
+// transact serves a request with fn in a transaction when enabled and a
+// transaction function is set. The transaction is committed if fn responds
+// with a success status and rolled back otherwise, including when fn panics
+// or skips the request because the client has gone away.
+func (s *Server) transact(w http.ResponseWriter, r *http.Request, enabled bool, fn func(w http.ResponseWriter, r *http.Request)) {
+	if !enabled || s.transaction == nil {
+		fn(w, r)
+		return
+	}
+
+	ctx, commit, rollback, err := s.transaction(r.Context())
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	r = r.WithContext(context.WithValue(ctx, transactionKey, true))
+
+	tw := &transactionWriter{ResponseWriter: w}
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		if err := rollback(); err != nil {
+			s.logger.Printf("Failed to roll back transaction: %v (%s %s)", err, r.Method, r.URL.Path)
+		}
+	}()
+	fn(tw, r)
+
+	if tw.status != 0 && tw.status < http.StatusBadRequest {
+		committed = true
+		if err := commit(); err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+	}
+	if tw.status != 0 {
+		w.WriteHeader(tw.status)
+		w.Write(tw.body.Bytes())
+	}
+}
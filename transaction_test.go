@@ -0,0 +1,149 @@
+package reason
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type txKey struct{}
+
+// testTx holds the names created in a transaction until it is committed.
+type testTx struct {
+	pending []string
+}
+
+type TxHandler struct {
+	TestResourceHandler
+	ctx context.Context
+}
+
+func (th TxHandler) Path() string {
+	return "tx"
+}
+
+func (th TxHandler) WithContext(ctx context.Context) ResourceHandler {
+	return TxHandler{ctx: ctx}
+}
+
+func (th TxHandler) CreateResource(resource interface{}) (interface{}, error) {
+	if err := th.CreateResources([]interface{}{resource}); err != nil {
+		return nil, err
+	}
+	return th.TestResourceHandler.CreateResource(resource)
+}
+
+func (th TxHandler) CreateResources(resources []interface{}) error {
+	tx, ok := th.ctx.Value(txKey{}).(*testTx)
+	if !ok {
+		return errors.New("no transaction")
+	}
+	for _, res := range resources {
+		name := res.(TestResource).Name
+		if name == "fail" {
+			return &APIError{Status: http.StatusConflict, Code: "conflict", Err: errors.New("failed")}
+		}
+		tx.pending = append(tx.pending, name)
+	}
+	return nil
+}
+
+func TestTransaction(t *testing.T) {
+	var requests = []struct {
+		ContentType string
+		Body        string
+		StatusCode  int
+		Response    string
+		Store       []string
+	}{
+		{"application/json", bulkBody(150, ""), 201, `{"created":150}`, []string{"item 0", "item 149"}},
+		{"application/json", bulkBody(150, `{"name":"fail"}`), 409, `{"created":0,"error":{"status":409,"code":"conflict"}}`, nil},
+		{"application/x-www-form-urlencoded", "name=single", 201, `{"id":3,"name":"single"}`, []string{"single", "single"}},
+		{"application/x-www-form-urlencoded", "name=fail", 409, `{"error":{"status":409,"code":"conflict"}}`, nil},
+	}
+
+	var store []string
+	var rollbacks int
+	s := New()
+	s.SetTransaction(func(ctx context.Context) (context.Context, func() error, func() error, error) {
+		tx := &testTx{}
+		commit := func() error {
+			store = append(store, tx.pending...)
+			return nil
+		}
+		rollback := func() error {
+			rollbacks++
+			return nil
+		}
+		return context.WithValue(ctx, txKey{}, tx), commit, rollback, nil
+	})
+	s.Add(TestResource{}, TxHandler{}, WithTransaction())
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for k, request := range requests {
+		store, rollbacks = nil, 0
+		res, err := http.Post(ts.URL+"/tx", request.ContentType, strings.NewReader(request.Body))
+		if err != nil {
+			t.Fatalf("%d: expected no error from request, got %s", k, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%d: expected status code %d, got %d", k, request.StatusCode, res.StatusCode)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%d: expected no error from read, got %s", k, err.Error())
+		}
+		if string(body) != request.Response {
+			t.Errorf("%d: expected body '%s', got '%s'", k, request.Response, body)
+		}
+
+		var committed []string
+		if len(store) > 0 {
+			committed = []string{store[0], store[len(store)-1]}
+		}
+		if fmt.Sprint(committed) != fmt.Sprint(request.Store) {
+			t.Errorf("%d: expected committed %v, got %v", k, request.Store, committed)
+		}
+		if expected := len(request.Store) == 0; (rollbacks == 1) != expected {
+			t.Errorf("%d: expected rollback %t, got %d rollbacks", k, expected, rollbacks)
+		}
+	}
+}
+
+func TestTransactionCommitError(t *testing.T) {
+	var logs bytes.Buffer
+	s := New(WithLogger(log.New(&logs, "", 0)))
+	s.SetTransaction(func(ctx context.Context) (context.Context, func() error, func() error, error) {
+		commit := func() error {
+			return errors.New("commit failed")
+		}
+		rollback := func() error {
+			return nil
+		}
+		return context.WithValue(ctx, txKey{}, &testTx{}), commit, rollback, nil
+	})
+	s.Add(TestResource{}, TxHandler{}, WithTransaction())
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/tx", strings.NewReader("name=single"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	s.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status code 500, got %d", rec.Code)
+	}
+	if !strings.Contains(logs.String(), "commit failed") {
+		t.Errorf("expected commit error to be logged, got '%s'", logs.String())
+	}
+}
@@ -0,0 +1,69 @@
+package reason
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+)
+
+// OperationValidator implementers validate the data parsed from a request
+// before it is passed to CreateResource or UpdateResource. op tells the
+// operations apart, so validation shared by creates and updates can be written
+// once. Returning a ValidationError causes the server to respond with
+// http.StatusUnprocessableEntity.
+type OperationValidator interface {
+	Validate(ctx context.Context, data interface{}, op Operation) error
+}
+
+// Validator can be implemented by resource schemas to check their own fields,
+// independently of the handler that stores them. Validate is called on the
+// data parsed from create and update requests, after the OperationValidator
+// of the handler. Errors that do not choose a status, such as those of
+// errors.New, respond with http.StatusUnprocessableEntity and their message.
+type Validator interface {
+	Validate() error
+}
+
+// ValidationError reports data that failed validation.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// validate runs the handler's validation for an operation and the validation
+// of the data, if they have any.
+func validate(r *http.Request, handler interface{}, data interface{}, op Operation) error {
+	if validator, ok := handler.(OperationValidator); ok {
+		if err := validator.Validate(r.Context(), data, op); err != nil {
+			return err
+		}
+	}
+	return validateData(data)
+}
+
+// validateData runs the Validate method of data, which may be declared on
+// a pointer to its type.
+func validateData(data interface{}) error {
+	validator, ok := data.(Validator)
+	if !ok && data != nil {
+		ptr := reflect.New(reflect.TypeOf(data))
+		ptr.Elem().Set(reflect.ValueOf(data))
+		validator, ok = ptr.Interface().(Validator)
+	}
+	if !ok {
+		return nil
+	}
+
+	err := validator.Validate()
+	if err == nil || errorStatus(err) != http.StatusInternalServerError {
+		return err
+	}
+	return &ValidationError{Message: err.Error()}
+}
@@ -0,0 +1,143 @@
+package reason
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type ValidatingHandler struct {
+	TestResourceHandler
+}
+
+func (vh ValidatingHandler) Path() string {
+	return "valid"
+}
+
+func (vh ValidatingHandler) Validate(ctx context.Context, data interface{}, op Operation) error {
+	tr := data.(TestResource)
+	if tr.Name == "" {
+		return &ValidationError{Field: "name", Message: "Name is required"}
+	}
+	if op == OperationUpdate && tr.Name == "New Test" {
+		return &ValidationError{Field: "name", Message: "Name cannot be changed to " + tr.Name}
+	}
+	return nil
+}
+
+func TestOperationValidator(t *testing.T) {
+	var requests = []struct {
+		Path       string
+		Name       string
+		StatusCode int
+		Body       string
+	}{
+		{"/valid", "New Test", 201, `{"id":3,"name":"New Test"}`},
+		{"/valid", "", 422, `{"error":{"status":422,"code":"invalid","message":"Name is required","field":"name"}}`},
+		{"/valid/1", "Updated Test", 200, `{"id":1,"name":"Updated Test"}`},
+		{"/valid/1", "", 422, `{"error":{"status":422,"code":"invalid","message":"Name is required","field":"name"}}`},
+		{"/valid/1", "New Test", 422, `{"error":{"status":422,"code":"invalid","message":"Name cannot be changed to New Test","field":"name"}}`},
+		{"/test", "", 201, `{"id":3,"name":""}`},
+	}
+
+	s := New()
+	s.Add(TestResource{}, ValidatingHandler{})
+	s.Add(TestResource{}, TestResourceHandler{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, request := range requests {
+		res, err := http.PostForm(ts.URL+request.Path, url.Values{"name": {request.Name}})
+		if err != nil {
+			t.Errorf("%s: expected no error from PostForm, got %s", request.Path, err.Error())
+		}
+
+		if res.StatusCode != request.StatusCode {
+			t.Errorf("%s: expected status code %d, got %d", request.Path, request.StatusCode, res.StatusCode)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("%s: expected no error from read, got %s", request.Path, err.Error())
+		}
+
+		if string(body) != request.Body {
+			t.Errorf("%s: expected body '%s', got '%s'", request.Path, request.Body, body)
+		}
+	}
+}
+
+type SelfValidatingResource struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func (sv *SelfValidatingResource) Validate() error {
+	if sv.Name == "" {
+		return errors.New("Name is required")
+	}
+	if sv.Name == "admin" {
+		return &ValidationError{Field: "name", Message: "Name is reserved"}
+	}
+	return nil
+}
+
+type SelfValidatingHandler struct{}
+
+func (sh SelfValidatingHandler) Path() string {
+	return "selfvalid"
+}
+
+func (sh SelfValidatingHandler) GetResource(id string) (interface{}, error) {
+	return SelfValidatingResource{ID: 1, Name: "The Test"}, nil
+}
+
+func (sh SelfValidatingHandler) CreateResource(resource interface{}) (interface{}, error) {
+	sv := resource.(SelfValidatingResource)
+	sv.ID = 3
+	return sv, nil
+}
+
+func (sh SelfValidatingHandler) UpdateResource(resource interface{}, data interface{}) (interface{}, error) {
+	sv := resource.(SelfValidatingResource)
+	sv.Name = data.(SelfValidatingResource).Name
+	return sv, nil
+}
+
+func TestValidator(t *testing.T) {
+	var requests = []struct {
+		Path       string
+		Name       string
+		StatusCode int
+		Body       string
+	}{
+		{"/selfvalid", "New Test", 201, `{"id":3,"name":"New Test"}`},
+		{"/selfvalid", "", 422, `{"error":{"status":422,"code":"invalid","message":"Name is required"}}`},
+		{"/selfvalid", "admin", 422, `{"error":{"status":422,"code":"invalid","message":"Name is reserved","field":"name"}}`},
+		{"/selfvalid/1", "Updated Test", 200, `{"id":1,"name":"Updated Test"}`},
+		{"/selfvalid/1", "", 422, `{"error":{"status":422,"code":"invalid","message":"Name is required"}}`},
+	}
+
+	s := New()
+	s.Add(SelfValidatingResource{}, SelfValidatingHandler{})
+
+	for _, request := range requests {
+		r := httptest.NewRequest("POST", request.Path, strings.NewReader(url.Values{"name": {request.Name}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+
+		if w.Code != request.StatusCode {
+			t.Errorf("%s %q: expected status code %d, got %d", request.Path, request.Name, request.StatusCode, w.Code)
+		}
+		if body := w.Body.String(); body != request.Body {
+			t.Errorf("%s %q: expected body '%s', got '%s'", request.Path, request.Name, request.Body, body)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package reason
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Webhook delivery defaults: each attempt times out after webhookTimeout and
+// failed attempts are retried up to webhookAttempts times in total, waiting
+// webhookBackoff before the first retry and doubling the wait after each.
+const (
+	webhookTimeout  = 10 * time.Second
+	webhookAttempts = 3
+	webhookBackoff  = time.Second
+)
+
+// WebhookEvent is the JSON body posted to a webhook after a resource is
+// created, updated or deleted, for example:
+//
+//	{"event":"create","resource":"users","id":"3","data":{"id":3,"name":"Jo"}}
+type WebhookEvent struct {
+	Event    Operation   `json:"event"`
+	Resource string      `json:"resource"`
+	ID       string      `json:"id,omitempty"`
+	Data     interface{} `json:"data,omitempty"`
+}
+
+// SetWebhook posts a WebhookEvent to url after each successful create, update
+// or delete, or only after the given events. Events are delivered in the
+// background and retried with backoff on network errors and server errors,
+// failures are logged and never affect the response. An empty url disables
+// the webhook.
+func (s *Server) SetWebhook(url string, events ...Operation) {
+	if url == "" {
+		s.webhook = nil
+		return
+	}
+	if len(events) == 0 {
+		events = []Operation{OperationCreate, OperationUpdate, OperationDelete}
+	}
+	w := &webhook{
+		url:      url,
+		events:   make(map[Operation]bool),
+		client:   &http.Client{Timeout: webhookTimeout},
+		attempts: webhookAttempts,
+		backoff:  webhookBackoff,
+	}
+	for _, event := range events {
+		w.events[event] = true
+	}
+	s.webhook = w
+}
+
+type webhook struct {
+	url      string
+	events   map[Operation]bool
+	client   *http.Client
+	attempts int
+	backoff  time.Duration
+}
+
+// notify delivers an event for the resource res of reg to the webhook, if
+// one is set for the event. The event is encoded before returning, so the
+// handler may go on to modify res.
+func (s *Server) notify(reg *registration, event Operation, id string, res interface{}) {
+	w := s.webhook
+	if w == nil || !w.events[event] {
+		return
+	}
+
+	body, err := json.Marshal(WebhookEvent{
+		Event:    event,
+		Resource: reg.path,
+		ID:       id,
+		Data:     res,
+	})
+	if err != nil {
+		s.logger.Printf("Failed to marshal %s webhook for %s to JSON: %v", event, reg.path, err)
+		return
+	}
+
+	go func() {
+		if err := w.deliver(body); err != nil {
+			s.logger.Printf("Failed to deliver %s webhook for %s: %v", event, reg.path, err)
+		}
+	}()
+}
+
+// deliver posts body to the webhook, retrying failed attempts. Responses with
+// a client error status are not retried.
+func (w *webhook) deliver(body []byte) error {
+	var err error
+	wait := w.backoff
+	for attempt := 1; attempt <= w.attempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(wait)
+			wait *= 2
+		}
+
+		var res *http.Response
+		res, err = w.client.Post(w.url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			continue
+		}
+		res.Body.Close()
+		if res.StatusCode < 300 {
+			return nil
+		}
+		err = fmt.Errorf("unexpected status %s", res.Status)
+		if res.StatusCode < 500 && res.StatusCode != http.StatusTooManyRequests {
+			return err
+		}
+	}
+	return err
+}
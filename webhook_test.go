@@ -0,0 +1,82 @@
+package reason
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestWebhook(t *testing.T) {
+	events := make(chan string, 10)
+	failed := false
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fail the first delivery to have it retried
+		if !failed {
+			failed = true
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		events <- string(body)
+	}))
+	defer hook.Close()
+
+	s := New()
+	s.SetWebhook(hook.URL, OperationCreate, OperationDelete)
+	s.webhook.backoff = time.Millisecond
+	s.Add(TestResource{}, NewMapStore("hooked"))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	var requests = []struct {
+		Method string
+		Path   string
+		Data   url.Values
+		Event  string
+	}{
+		{"POST", "/hooked", url.Values{"name": {"Hooked"}}, `{"event":"create","resource":"hooked","id":"1","data":{"id":1,"name":"Hooked"}}`},
+		{"POST", "/hooked/1", url.Values{"name": {"Updated"}}, ``},
+		{"DELETE", "/hooked/1", nil, `{"event":"delete","resource":"hooked","id":"1","data":{"id":1,"name":"Updated"}}`},
+	}
+
+	for _, request := range requests {
+		var res *http.Response
+		var err error
+		switch request.Method {
+		case "POST":
+			res, err = http.PostForm(ts.URL+request.Path, request.Data)
+		default:
+			var req *http.Request
+			req, err = http.NewRequest(request.Method, ts.URL+request.Path, nil)
+			if err != nil {
+				t.Fatalf("%s %s: expected no error from http.NewRequest, got %s", request.Method, request.Path, err.Error())
+			}
+			res, err = http.DefaultClient.Do(req)
+		}
+		if err != nil {
+			t.Fatalf("%s %s: expected no error from request, got %s", request.Method, request.Path, err.Error())
+		}
+		res.Body.Close()
+
+		if request.Event == "" {
+			continue
+		}
+		select {
+		case event := <-events:
+			if event != request.Event {
+				t.Errorf("%s %s: expected event '%s', got '%s'", request.Method, request.Path, request.Event, event)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s %s: expected event '%s', got none", request.Method, request.Path, request.Event)
+		}
+	}
+
+	select {
+	case event := <-events:
+		t.Errorf("expected no more events, got '%s'", event)
+	default:
+	}
+}
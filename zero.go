@@ -0,0 +1,65 @@
+package reason
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// ZeroPolicy controls whether fields holding zero values are written in the
+// JSON representation of resources.
+type ZeroPolicy int
+
+const (
+	// ZeroAsTagged leaves zero values to the omitempty option of each field,
+	// as encoding/json does.
+	ZeroAsTagged ZeroPolicy = iota
+	// ZeroOmit omits every field holding a zero value.
+	ZeroOmit
+	// ZeroInclude includes every field, ignoring omitempty.
+	ZeroInclude
+)
+
+// SetZeroPolicy sets how zero valued fields of resources are written. The
+// policy applies to the top level fields of struct resources, regardless of
+// their tags. The default is ZeroAsTagged.
+func (s *Server) SetZeroPolicy(policy ZeroPolicy) {
+	s.zeroPolicy = policy
+}
+
+// applyZeroPolicy builds the JSON representation of a struct resource from its
+// schema fields, omitting or including zero values according to the policy.
+func (s *Server) applyZeroPolicy(res interface{}) (interface{}, error) {
+	if _, ok := res.(json.Marshaler); ok {
+		return res, nil
+	}
+	val := reflect.Indirect(reflect.ValueOf(res))
+	if val.Kind() != reflect.Struct {
+		return res, nil
+	}
+
+	fields, err := s.getSchemaFields(schemaKey{typ: val.Type()})
+	if err != nil {
+		return nil, err
+	}
+
+	obj := &jsonObject{values: make(map[string]json.RawMessage, len(fields))}
+	for _, field := range fields {
+		value, ok := fieldValue(val, field.index)
+		if !ok || (s.zeroPolicy == ZeroOmit && value.IsZero()) {
+			continue
+		}
+		raw, err := json.Marshal(value.Interface())
+		if err != nil {
+			return nil, err
+		}
+		// Quoted fields are written as a JSON string of their JSON value,
+		// as by the ",string" tag option; nil pointers stay null
+		if field.quoted && string(raw) != "null" {
+			if raw, err = json.Marshal(string(raw)); err != nil {
+				return nil, err
+			}
+		}
+		obj.setRaw(field.name, raw)
+	}
+	return obj, nil
+}
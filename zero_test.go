@@ -0,0 +1,95 @@
+package reason
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type ZeroResource struct {
+	ID      int64             `json:"id"`
+	Name    string            `json:"name,omitempty"`
+	Tags    []string          `json:"tags"`
+	Meta    map[string]string `json:"meta,omitempty"`
+	Private string            `json:"-"`
+	hidden  string
+	TestResourceV2
+}
+
+func TestZeroPolicy(t *testing.T) {
+	resource := ZeroResource{ID: 1, Private: "secret", hidden: "hidden"}
+	resource.TestResourceV2.Title = "Dr"
+
+	var policies = []struct {
+		Policy ZeroPolicy
+		Body   string
+	}{
+		{ZeroAsTagged, `{"id":1,"tags":null,"title":"Dr"}`},
+		{ZeroOmit, `{"id":1,"title":"Dr"}`},
+		{ZeroInclude, `{"id":1,"name":"","tags":null,"meta":null,"title":"Dr"}`},
+	}
+
+	for _, policy := range policies {
+		s := New()
+		s.SetZeroPolicy(policy.Policy)
+
+		res, err := s.represent(&registration{}, nil, resource)
+		if err != nil {
+			t.Errorf("%d: expected no error from represent, got %s", policy.Policy, err.Error())
+		}
+		body, err := json.Marshal(res)
+		if err != nil {
+			t.Errorf("%d: expected no error from json.Marshal, got %s", policy.Policy, err.Error())
+		}
+
+		if string(body) != policy.Body {
+			t.Errorf("%d: expected body '%s', got '%s'", policy.Policy, policy.Body, body)
+		}
+	}
+}
+
+type ZeroBase struct {
+	Note string `json:"note"`
+	X    string
+	Y    string
+}
+
+type ZeroOther struct {
+	X string
+	Y string `json:"Y"`
+}
+
+type ZeroEmbedded struct {
+	ID    int64 `json:"id"`
+	Count int   `json:"count,string"`
+	*ZeroBase
+	ZeroOther
+}
+
+func TestZeroPolicyEmbedded(t *testing.T) {
+	resources := []ZeroEmbedded{
+		{ID: 1, Count: 2, ZeroBase: &ZeroBase{Note: "n", X: "a", Y: "b"}, ZeroOther: ZeroOther{X: "c", Y: "d"}},
+		{ID: 2, Count: 3},
+	}
+
+	s := New()
+	s.SetZeroPolicy(ZeroInclude)
+	for _, resource := range resources {
+		expected, err := json.Marshal(resource)
+		if err != nil {
+			t.Fatalf("%d: expected no error from json.Marshal, got %s", resource.ID, err.Error())
+		}
+
+		res, err := s.represent(&registration{}, nil, resource)
+		if err != nil {
+			t.Errorf("%d: expected no error from represent, got %s", resource.ID, err.Error())
+		}
+		body, err := json.Marshal(res)
+		if err != nil {
+			t.Errorf("%d: expected no error from json.Marshal, got %s", resource.ID, err.Error())
+		}
+
+		if string(body) != string(expected) {
+			t.Errorf("%d: expected body '%s', got '%s'", resource.ID, expected, body)
+		}
+	}
+}